@@ -1,12 +1,13 @@
 package gosml
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"runtime"
+	"strings"
 )
 
-var debugEnable bool
-
 const (
 	OCTET_MESSAGE_END       = 0x00
 	OCTET_TYPE_FIELD        = 0x70
@@ -20,19 +21,87 @@ const (
 	OCTET_OPTIONAL_SKIPPED  = 0x01
 )
 
+// ErrReservedType means that a TL field uses a type class that is reserved by the SML specification.
+// This usually indicates that parsing lost sync with the data.
+var ErrReservedType = errors.New("reserved type")
+
+// typeError returns the error for an unexpected type field found at the buffer's cursor
+func (buf *Buffer) typeError(typeField, expectedType uint8) error {
+	switch typeField {
+	case OCTET_TYPE_OCTET_STRING, OCTET_TYPE_BOOLEAN, OCTET_TYPE_INTEGER, OCTET_TYPE_UNSIGNED, OCTET_TYPE_LIST:
+		return fmt.Errorf("unexpected type %02x (expected %02x)", typeField, expectedType)
+	}
+	return fmt.Errorf("%w %02x at offset %d (expected %02x)", ErrReservedType, typeField, buf.Cursor, expectedType)
+}
+
 type Buffer struct {
 	Bytes  []byte
 	Cursor int
+
+	parseConfig
 }
 
+// parseConfig holds the settings of ReadOptions that affect parsing itself
+type parseConfig struct {
+	// entryFilter, if set, makes ListParse skip list entries whose objName it rejects
+	entryFilter func(objName OctetString) bool
+	// maxListEntries and maxValueLen, if positive, limit the number of entries of a list and the
+	// size of their values
+	maxListEntries int
+	maxValueLen    int
+	// trace, if set, receives a line per parse step, see Debug
+	trace io.Writer
+	// crcWarn keeps messages whose checksum doesn't match, see WithCrcWarn
+	crcWarn bool
+	// shortEntries reads list entries of 5 fields without status and valTime, see
+	// WithShortListEntries
+	shortEntries bool
+}
+
+// Debug writes the calling parse function, the cursor, the TL byte at the cursor and its type to the
+// trace writer set by WithTrace. Without trace writer it does nothing.
 func (buf *Buffer) Debug() {
-	if debugEnable {
-		pc, _, _, ok := runtime.Caller(1)
-		funcDetails := runtime.FuncForPC(pc)
-		if ok && funcDetails != nil {
-			fmt.Printf("%-22s % x\n", funcDetails.Name(), buf.Bytes[buf.Cursor:buf.Cursor+30])
+	if buf.trace == nil {
+		return
+	}
+
+	name := "?"
+	if pc, _, _, ok := runtime.Caller(1); ok {
+		if funcDetails := runtime.FuncForPC(pc); funcDetails != nil {
+			name = funcDetails.Name()
+			name = strings.TrimPrefix(name[strings.LastIndex(name, "/")+1:], "gosml.")
 		}
 	}
+
+	if buf.Cursor >= len(buf.Bytes) {
+		fmt.Fprintf(buf.trace, "%-34s offset %4d  end of data\n", name, buf.Cursor)
+		return
+	}
+	b := buf.GetCurrentByte()
+	fmt.Fprintf(buf.trace, "%-34s offset %4d  tl %02x  %s\n", name, buf.Cursor, b, tlTypeName(b))
+}
+
+// tlTypeName describes the element announced by TL byte b
+func tlTypeName(b byte) string {
+	switch b {
+	case OCTET_MESSAGE_END:
+		return "end of message"
+	case OCTET_OPTIONAL_SKIPPED:
+		return "skipped"
+	}
+	switch b & OCTET_TYPE_FIELD {
+	case OCTET_TYPE_OCTET_STRING:
+		return "octet string"
+	case OCTET_TYPE_BOOLEAN:
+		return "boolean"
+	case OCTET_TYPE_INTEGER:
+		return "integer"
+	case OCTET_TYPE_UNSIGNED:
+		return "unsigned"
+	case OCTET_TYPE_LIST:
+		return "list"
+	}
+	return "reserved type"
 }
 
 func (buf *Buffer) GetCurrentByte() byte {
@@ -57,7 +126,7 @@ func (buf *Buffer) Expect(expectedType uint8, expectedLength int) error {
 
 func (buf *Buffer) ExpectType(expectedType uint8) error {
 	if typeField := buf.GetNextType(); typeField != expectedType {
-		return fmt.Errorf("unexpected type %02x (expected %02x)", typeField, expectedType)
+		return buf.typeError(typeField, expectedType)
 	}
 
 	return nil
@@ -67,8 +136,11 @@ func (buf *Buffer) GetNextType() uint8 {
 	return buf.GetCurrentByte() & OCTET_TYPE_FIELD
 }
 
+// GetNextLength returns the length of the next element, i.e. the number of elements of lists and the
+// number of data bytes otherwise, and skips its TL bytes. Lengths may span several TL bytes, each
+// contributing 4 bits, e.g. 0x83 0x02 for an octet string of 0x32 - 2 = 48 bytes.
 func (buf *Buffer) GetNextLength() int {
-	var length uint8
+	var length int
 	var list int
 
 	b := buf.GetCurrentByte()
@@ -82,7 +154,7 @@ func (buf *Buffer) GetNextLength() int {
 		b := buf.GetCurrentByte()
 
 		length = length << 4
-		length = length | (b & OCTET_LENGTH_FIELD)
+		length = length | int(b&OCTET_LENGTH_FIELD)
 
 		if b&OCTET_ANOTHER_TL != OCTET_ANOTHER_TL {
 			break
@@ -99,7 +171,7 @@ func (buf *Buffer) GetNextLength() int {
 
 	buf.UpdateBytesRead(1)
 
-	return int(length) + list
+	return length + list
 }
 
 func (buf *Buffer) OptionalIsSkipped() bool {
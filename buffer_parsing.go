@@ -1,7 +1,6 @@
 package gosml
 
 import (
-	"encoding/binary"
 	"fmt"
 )
 
@@ -13,6 +12,8 @@ const (
 )
 
 func (buf *Buffer) BooleanParse() (bool, error) {
+	buf.Debug()
+
 	if buf.OptionalIsSkipped() {
 		return false, nil
 	}
@@ -66,55 +67,52 @@ func (buf *Buffer) I64Parse() (int64, error) {
 	return int64(num), err
 }
 
+// NumberParse parses a big-endian integer of numType occupying 1 to maxSize bytes. Integers are sign
+// extended, so widths that aren't a power of two (e.g. 6 byte energy registers) are read correctly.
 func (buf *Buffer) NumberParse(numType uint8, maxSize int) (int64, error) {
+	buf.Debug()
+
 	if skip := buf.OptionalIsSkipped(); skip {
 		return 0, nil
 	}
 
 	typeField := buf.GetNextType()
 	if typeField != numType {
-		return 0, fmt.Errorf("unexpected type %02x (expected %02x)", typeField, numType)
+		return 0, buf.typeError(typeField, numType)
+	}
+
+	switch maxSize {
+	case TYPE_NUMBER_8, TYPE_NUMBER_16, TYPE_NUMBER_32, TYPE_NUMBER_64:
+	default:
+		return 0, fmt.Errorf("invalid number type size %02x", maxSize)
 	}
 
 	length := buf.GetNextLength()
 	if length < 0 || length > maxSize {
 		return 0, fmt.Errorf("invalid length: %d", length)
 	}
-
-	np := make([]byte, maxSize)
-	missingBytes := maxSize - length
-
-	for i := 0; i < length; i++ {
-		np[missingBytes+i] = buf.Bytes[buf.Cursor+i]
+	if buf.Cursor+length > len(buf.Bytes) {
+		return 0, fmt.Errorf("number of %d bytes exceeds buffer at offset %d", length, buf.Cursor)
 	}
 
-	negativeInt := typeField == OCTET_TYPE_INTEGER && (typeField&128 > 0)
-	if negativeInt {
-		for i := 0; i < missingBytes; i++ {
-			np[i] = 0xFF
-		}
+	var num uint64
+	for _, b := range buf.Bytes[buf.Cursor : buf.Cursor+length] {
+		num = num<<8 | uint64(b)
 	}
 
-	var num int64
-	switch maxSize {
-	case TYPE_NUMBER_8:
-		num = int64(np[0])
-	case TYPE_NUMBER_16:
-		num = int64(int16(binary.BigEndian.Uint16(np)))
-	case TYPE_NUMBER_32:
-		num = int64(int32(binary.BigEndian.Uint32(np)))
-	case TYPE_NUMBER_64:
-		num = int64(binary.BigEndian.Uint64(np))
-	default:
-		return num, fmt.Errorf("invalid number type size %02x", maxSize)
+	// sign extension
+	if typeField == OCTET_TYPE_INTEGER && length > 0 && length < 8 && num&(1<<(8*length-1)) != 0 {
+		num |= ^uint64(0) << (8 * length)
 	}
 
 	buf.UpdateBytesRead(length)
 
-	return num, nil
+	return int64(num), nil
 }
 
 func (buf *Buffer) OctetStringParse() (OctetString, error) {
+	buf.Debug()
+
 	if skip := buf.OptionalIsSkipped(); skip {
 		return nil, nil
 	}
@@ -127,6 +125,9 @@ func (buf *Buffer) OctetStringParse() (OctetString, error) {
 	if length < 0 {
 		return nil, fmt.Errorf("invalid octet string length %d", length)
 	}
+	if buf.Cursor+length > len(buf.Bytes) {
+		return nil, fmt.Errorf("octet string of %d bytes exceeds buffer at offset %d", length, buf.Cursor)
+	}
 
 	str := buf.Bytes[buf.Cursor : buf.Cursor+length]
 	buf.UpdateBytesRead(length)
@@ -134,6 +135,22 @@ func (buf *Buffer) OctetStringParse() (OctetString, error) {
 	return str, nil
 }
 
+// ObjNameParse parses the objName of a list entry. Besides the plain octet string some meters wrap
+// it, e.g. a server id, in a choice of tag and octet string, the tag is dropped.
+func (buf *Buffer) ObjNameParse() (OctetString, error) {
+	if buf.Cursor >= len(buf.Bytes) || buf.GetNextType() != OCTET_TYPE_LIST {
+		return buf.OctetStringParse()
+	}
+
+	if err := buf.Expect(OCTET_TYPE_LIST, 2); err != nil {
+		return nil, fmt.Errorf("choice: %w", err)
+	}
+	if _, err := buf.U8Parse(); err != nil {
+		return nil, fmt.Errorf("choice tag: %w", err)
+	}
+	return buf.OctetStringParse()
+}
+
 func (buf *Buffer) StatusParse() (int64, error) {
 	/*
 		if (BufOptionalIsSkipped(buf)) {
@@ -161,36 +178,44 @@ func (buf *Buffer) StatusParse() (int64, error) {
 				break;
 		}
 	*/
-	// TODO proper type handling
-
-	if skip := buf.OptionalIsSkipped(); skip {
-		return 0, nil
-	}
+	status, _, err := buf.StatusRawParse()
+	return status, err
+}
 
+// StatusRawParse parses a status word along with its raw bytes. Meters encode the status as unsigned
+// of any width from u8 to u64 or as octet string of up to 8 bytes, which is read as big endian
+// number. A skipped status is reported as 0 with nil raw bytes.
+func (buf *Buffer) StatusRawParse() (int64, OctetString, error) {
 	buf.Debug()
 
-	var max uint8 = 1
-	var status8 int64
-	typeField := buf.GetNextType()
-	statusType := typeField
-	b := buf.GetCurrentByte()
+	if skip := buf.OptionalIsSkipped(); skip {
+		return 0, nil, nil
+	}
 
-	if typeField == OCTET_TYPE_UNSIGNED {
-		// get maximal size, if not all bytes are used (example: only 6 bytes for a u64)
-		for max < ((b & OCTET_LENGTH_FIELD) - 1) {
-			max = max << 1
+	switch typeField := buf.GetNextType(); typeField {
+	case OCTET_TYPE_UNSIGNED:
+		start := buf.Cursor + buf.tlLength()
+		status, err := buf.NumberParse(typeField, TYPE_NUMBER_64)
+		if err != nil {
+			return 0, nil, err
 		}
-
-		if _, err := buf.NumberParse(typeField, int(max)); err != nil {
-			return 0, err
+		return status, buf.Bytes[start:buf.Cursor], nil
+	case OCTET_TYPE_OCTET_STRING:
+		raw, err := buf.OctetStringParse()
+		if err != nil {
+			return 0, nil, err
 		}
-
-		statusType = statusType | max
-	} else {
-		return 0, fmt.Errorf("unexpected type %02x (expected %02x)", typeField, OCTET_TYPE_UNSIGNED)
+		if len(raw) > 8 {
+			return 0, nil, fmt.Errorf("status of %d bytes exceeds 64 bit", len(raw))
+		}
+		var status uint64
+		for _, b := range raw {
+			status = status<<8 | uint64(b)
+		}
+		return int64(status), raw, nil
+	default:
+		return 0, nil, buf.typeError(typeField, OCTET_TYPE_UNSIGNED)
 	}
-
-	return status8, nil
 }
 
 func (buf *Buffer) TimeParse() (Time, error) {
@@ -239,50 +264,8 @@ func (buf *Buffer) TimeParse() (Time, error) {
 			goto error;
 		}
 	*/
-	// TODO return proper timestamps
-
-	if skip := buf.OptionalIsSkipped(); skip {
-		return 0, nil
-	}
-
-	if err := buf.Expect(OCTET_TYPE_LIST, 2); err != nil {
-		return 0, err
-	}
-
-	// time.tag
-	if _, err := buf.U8Parse(); err != nil {
-		return 0, err
-	}
-
-	var timestamp uint32
-	var err error
-
-	typeField := buf.GetNextType()
-	switch typeField {
-	case OCTET_TYPE_UNSIGNED:
-		if timestamp, err = buf.U32Parse(); err != nil {
-			return 0, err
-		}
-	case OCTET_TYPE_LIST:
-		// Some meters (e.g. FROETEC Multiflex ZG22) giving not one uint32
-		// as timestamp, but a list of 3 values.
-		// Ignoring these values, so that parsing does not fail.
-		buf.GetNextLength() // should we check the length here?
-
-		if _, err := buf.U32Parse(); err != nil {
-			return 0, err
-		}
-		if _, err := buf.I16Parse(); err != nil {
-			return 0, err
-		}
-		if _, err := buf.I16Parse(); err != nil {
-			return 0, err
-		}
-	default:
-		return 0, fmt.Errorf("invalid time format %02x", typeField)
-	}
-
-	return Time(timestamp), nil
+	timestamp, _, _, err := buf.TimeChoiceParse()
+	return timestamp, err
 }
 
 func (buf *Buffer) ValueParse() (Value, error) {
@@ -320,12 +303,17 @@ func (buf *Buffer) ValueParse() (Value, error) {
 				break;
 		}
 	*/
+	buf.Debug()
+
 	value := Value{}
 
 	if buf.OptionalIsSkipped() {
 		return value, nil
 	}
 
+	start := buf.Cursor
+	tlLen := buf.tlLength()
+
 	typeField := buf.GetNextType()
 	b := buf.GetCurrentByte()
 
@@ -346,7 +334,7 @@ func (buf *Buffer) ValueParse() (Value, error) {
 		}
 	case OCTET_TYPE_UNSIGNED:
 		// get maximal size, if not all bytes are used (example: only 6 bytes for a u64)
-		for max < int((b&OCTET_LENGTH_FIELD)-1) {
+		for max < int(b&OCTET_LENGTH_FIELD)-1 {
 			max = max << 1
 		}
 
@@ -358,7 +346,7 @@ func (buf *Buffer) ValueParse() (Value, error) {
 		value.Typ = value.Typ | uint8(max)
 	case OCTET_TYPE_INTEGER:
 		// get maximal size, if not all bytes are used (example: only 6 bytes for a u64)
-		for max < int((b&OCTET_LENGTH_FIELD)-1) {
+		for max < int(b&OCTET_LENGTH_FIELD)-1 {
 			max = max << 1
 		}
 
@@ -368,9 +356,50 @@ func (buf *Buffer) ValueParse() (Value, error) {
 		}
 
 		value.Typ = value.Typ | uint8(max)
+	case OCTET_TYPE_LIST:
+		// compound value (e.g. a COSEM value carrying its own scaler and unit), only kept raw
+		if err = buf.skipElement(); err != nil {
+			return value, err
+		}
 	default:
-		return value, fmt.Errorf("unexpected type %02x", typeField)
+		return value, fmt.Errorf("%w %02x at offset %d", ErrReservedType, typeField, buf.Cursor)
 	}
 
+	value.Raw = buf.Bytes[start+tlLen : buf.Cursor]
+
 	return value, nil
 }
+
+// tlLength returns the number of TL bytes of the next element
+func (buf *Buffer) tlLength() int {
+	n := 1
+	for buf.Cursor+n <= len(buf.Bytes) && buf.Bytes[buf.Cursor+n-1]&OCTET_ANOTHER_TL != 0 {
+		n++
+	}
+	return n
+}
+
+// skipElement skips the next element including all elements of lists
+func (buf *Buffer) skipElement() error {
+	buf.Debug()
+
+	if buf.Cursor >= len(buf.Bytes) {
+		return fmt.Errorf("unexpected end of data at offset %d", buf.Cursor)
+	}
+
+	if buf.GetNextType() == OCTET_TYPE_LIST {
+		for elems := buf.GetNextLength(); elems > 0; elems-- {
+			if err := buf.skipElement(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	length := buf.GetNextLength()
+	if length < 0 || buf.Cursor+length > len(buf.Bytes) {
+		return fmt.Errorf("invalid length %d at offset %d", length, buf.Cursor)
+	}
+	buf.UpdateBytesRead(length)
+	return nil
+}
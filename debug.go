@@ -0,0 +1,71 @@
+package gosml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// String lists all registered OBIS code prefixes along with their number of callbacks, one per line.
+// Prefixes are rendered as dot separated decimal bytes, "*" stands for the empty prefix matching
+// all entries or for a wildcard group of a pattern.
+func (oc *obisGroupCallback) String() string {
+	var sb strings.Builder
+	oc.dump(&sb, nil)
+	return sb.String()
+}
+
+func (oc *obisGroupCallback) dump(sb *strings.Builder, prefix []string) {
+	if len(oc.callbacks) > 0 {
+		name := "*"
+		if len(prefix) > 0 {
+			name = strings.Join(prefix, ".")
+		}
+		fmt.Fprintf(sb, "%s: %d callback(s)\n", name, len(oc.callbacks))
+	}
+
+	keys := make([]int, 0, len(oc.childGroups))
+	for key := range oc.childGroups {
+		keys = append(keys, int(key))
+	}
+	sort.Ints(keys)
+	for _, key := range keys {
+		oc.childGroups[byte(key)].dump(sb, append(prefix, fmt.Sprint(key)))
+	}
+	if oc.wildcardGroup != nil {
+		oc.wildcardGroup.dump(sb, append(prefix, "*"))
+	}
+}
+
+// DumpCallbacks lists the OBIS code prefixes registered by the given options along with their number
+// of callbacks, followed by the ones registered with WithObisCallbackAll. This helps to verify that
+// the codes passed to WithObisCallback match the ones a meter sends.
+func DumpCallbacks(opts ...ReadOption) string {
+	options := newOptions(opts)
+	var sb strings.Builder
+	if options.topLevelCallback != nil {
+		sb.WriteString(options.topLevelCallback.String())
+	}
+
+	counts := map[string]int{}
+	var names []string
+	for _, cb := range options.allCallbacks {
+		name := "*"
+		if len(cb.obisCode) > 0 {
+			groups := make([]string, len(cb.obisCode))
+			for i, b := range cb.obisCode {
+				groups[i] = fmt.Sprint(b)
+			}
+			name = strings.Join(groups, ".")
+		}
+		if counts[name] == 0 {
+			names = append(names, name)
+		}
+		counts[name]++
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&sb, "%s: %d callback(s) for all entries\n", name, counts[name])
+	}
+	return sb.String()
+}
@@ -0,0 +1,61 @@
+package gosml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// RegisterDecoder decodes the value of a register into a structured type, e.g. the status flags a
+// manufacturer packs into an octet string. Decoded values implementing fmt.Stringer are rendered
+// by ValueString with their String method.
+type RegisterDecoder interface {
+	Decode(value Value) (interface{}, error)
+}
+
+// RegisterDecoderFunc adapts a function to RegisterDecoder
+type RegisterDecoderFunc func(value Value) (interface{}, error)
+
+func (fn RegisterDecoderFunc) Decode(value Value) (interface{}, error) {
+	return fn(value)
+}
+
+// ErrNoDecoder means that no RegisterDecoder was registered for a list entry's OBIS code
+var ErrNoDecoder = errors.New("no register decoder")
+
+type obisDecoder struct {
+	obisCode OctetString
+	decoder  RegisterDecoder
+}
+
+// WithRegisterDecoder sets dec as decoder of all list entries whose OBIS code starts with obisCode,
+// e.g. 1-0:96.50 for vendor registers. ValueDecoded returns the decoded value and ValueString
+// renders it. If several decoders match, the one registered last wins.
+func WithRegisterDecoder(obisCode OctetString, dec RegisterDecoder) ReadOption {
+	return func(o *options) {
+		o.decoders = append(o.decoders, obisDecoder{obisCode: obisCode, decoder: dec})
+	}
+}
+
+// applyDecoder sets the decoder of a list entry to the last registered decoder whose OBIS code
+// prefixes the entry's
+func (o *options) applyDecoder(le *ListEntry) {
+	for _, d := range o.decoders {
+		if bytes.HasPrefix(le.ObjName, d.obisCode) {
+			le.decoder = d.decoder
+		}
+	}
+}
+
+// ValueDecoded returns the entry's value decoded by the decoder set with WithRegisterDecoder, or
+// ErrNoDecoder if there is none
+func (le *ListEntry) ValueDecoded() (interface{}, error) {
+	if le.decoder == nil {
+		return nil, ErrNoDecoder
+	}
+	v, err := le.decoder.Decode(le.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", le.ObjectName(), err)
+	}
+	return v, nil
+}
@@ -0,0 +1,86 @@
+package gosml
+
+import (
+	"bufio"
+	"fmt"
+)
+
+// DeviceInfo describes a meter by its identification registers. Octet string values are decoded as
+// text if printable, otherwise rendered in hex. Fields are empty if the meter doesn't send the
+// corresponding register.
+type DeviceInfo struct {
+	ServerID        string // server id of the GetListResponse, see FormatServerID
+	SerialID        string // 1-0:96.1.0*255 or 1-0:0.0.9*255, server ids are formatted like ServerID
+	Manufacturer    string // 1-0:96.50.1*1 or 129-129:199.130.3*255
+	FirmwareVersion string // 1-0:0.2.0*255 or 1-0:96.90.2*1
+
+	// Identification holds all octet string registers of the frame by OBIS code, including the
+	// ones above
+	Identification map[string]string
+}
+
+var (
+	deviceInfoSerialCodes       = []string{"1-0:96.1.0*255", "1-0:0.0.9*255"}
+	deviceInfoManufacturerCodes = []string{"1-0:96.50.1*1", "129-129:199.130.3*255"}
+	deviceInfoFirmwareCodes     = []string{"1-0:0.2.0*255", "1-0:96.90.2*1"}
+)
+
+// ReadDeviceInfo reads from the buffered reader until the first SML file containing a
+// GetListResponse has been parsed and extracts the meter's identification from it. If the reader
+// is exhausted before such a file has been found io.EOF is returned.
+func ReadDeviceInfo(r *bufio.Reader) (DeviceInfo, error) {
+	lists, err := readLists(r)
+	if err != nil {
+		return DeviceInfo{}, err
+	}
+	return deviceInfo(lists), nil
+}
+
+func deviceInfo(lists []GetListResponse) DeviceInfo {
+	info := DeviceInfo{
+		ServerID:       FormatServerID(lists[0].ServerID),
+		Identification: map[string]string{},
+	}
+	raw := map[string]OctetString{}
+	for _, list := range lists {
+		for _, elem := range list.ValList {
+			if elem.Value.Typ == OCTET_TYPE_OCTET_STRING && len(elem.ObjName) == 6 {
+				raw[elem.ObjectName()] = elem.Value.DataBytes
+				info.Identification[elem.ObjectName()] = octetText(elem.Value.DataBytes)
+			}
+		}
+	}
+	if id, ok := firstOf(raw, deviceInfoSerialCodes); ok {
+		// many meters send their server id here
+		info.SerialID = FormatServerID(id)
+		if len(id) != 10 {
+			info.SerialID = octetText(id)
+		}
+	}
+	if s, ok := firstOf(raw, deviceInfoManufacturerCodes); ok {
+		info.Manufacturer = octetText(s)
+	}
+	if s, ok := firstOf(raw, deviceInfoFirmwareCodes); ok {
+		info.FirmwareVersion = octetText(s)
+	}
+	return info
+}
+
+// octetText returns s as text if it consists of printable ASCII characters only, otherwise in hex
+func octetText(s OctetString) string {
+	for _, c := range s {
+		if c < 0x20 || c > 0x7e {
+			return fmt.Sprintf("%x", []byte(s))
+		}
+	}
+	return string(s)
+}
+
+func firstOf(values map[string]OctetString, keys []string) (OctetString, bool) {
+	for _, key := range keys {
+		if v, ok := values[key]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
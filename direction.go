@@ -0,0 +1,45 @@
+package gosml
+
+// Direction is the energy flow direction of a list entry
+type Direction uint8
+
+const (
+	DIRECTION_UNKNOWN Direction = iota
+	DIRECTION_IMPORT            // energy taken from the grid (+A)
+	DIRECTION_EXPORT            // energy fed into the grid (-A)
+)
+
+// STATUS_ENERGY_DIRECTION is the bit of the status word of FNN basic meters (EDL) set while energy
+// is fed into the grid
+const STATUS_ENERGY_DIRECTION = 0x20
+
+func (d Direction) String() string {
+	switch d {
+	case DIRECTION_IMPORT:
+		return "import"
+	case DIRECTION_EXPORT:
+		return "export"
+	}
+	return "unknown"
+}
+
+// Direction returns the energy flow direction of the entry. Entries of the quantities +A (OBIS
+// c-field 1) and -A (c-field 2) are import and export by definition. For other entries, e.g. the
+// combined active power 16.7.0, the direction is taken from the status word if present.
+func (le *ListEntry) Direction() Direction {
+	if _, _, c, _, _, _, ok := le.ObisFields(); ok {
+		switch c {
+		case 1:
+			return DIRECTION_IMPORT
+		case 2:
+			return DIRECTION_EXPORT
+		}
+	}
+	if le.hasStatus {
+		if le.status&STATUS_ENERGY_DIRECTION != 0 {
+			return DIRECTION_EXPORT
+		}
+		return DIRECTION_IMPORT
+	}
+	return DIRECTION_UNKNOWN
+}
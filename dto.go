@@ -0,0 +1,44 @@
+package gosml
+
+import (
+	"encoding/hex"
+)
+
+// MeterMessage is a flat representation of a GetListResponse with serialization-friendly fields.
+// It decouples transport layers, e.g. gRPC services with their own protobuf messages, from the SML
+// internals.
+type MeterMessage struct {
+	ServerID  string         `json:"serverId"`  // hex encoded server id
+	Timestamp int64          `json:"timestamp"` // unix seconds of the sensor time, 0 if unknown
+	Readings  []MeterReading `json:"readings"`
+}
+
+// MeterReading is a single numeric reading of a MeterMessage
+type MeterReading struct {
+	Obis  string  `json:"obis"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// ToMeterMessage converts the numeric entries of list to a MeterMessage. The timestamp is only set
+// for (local) timestamps, sec indexes can't be converted.
+func ToMeterMessage(list *GetListResponse) MeterMessage {
+	msg := MeterMessage{
+		ServerID: hex.EncodeToString(list.ServerID),
+		Readings: []MeterReading{},
+	}
+	if t, _ := list.SensorTime(); !t.IsZero() {
+		msg.Timestamp = t.Unix()
+	}
+	for _, elem := range list.ValList {
+		if len(elem.ObjName) == 0 || !elem.IsNumeric() {
+			continue
+		}
+		msg.Readings = append(msg.Readings, MeterReading{
+			Obis:  elem.ObjectName(),
+			Value: elem.Float(),
+			Unit:  elem.UnitString(),
+		})
+	}
+	return msg
+}
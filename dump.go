@@ -0,0 +1,288 @@
+package gosml
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// dumpField describes an SML element for AnnotatedDump: its name and, for lists, the descriptions of
+// its elements, of its repeated elements or, for message bodies, of the data chosen by the tag
+type dumpField struct {
+	name   string
+	fields []*dumpField
+	each   *dumpField
+	choice map[uint64]*dumpField
+	crc    bool
+}
+
+func dumpLeaf(name string) *dumpField {
+	return &dumpField{name: name}
+}
+
+func dumpList(name string, fields ...*dumpField) *dumpField {
+	return &dumpField{name: name, fields: fields}
+}
+
+func dumpTime(name string) *dumpField {
+	return dumpList(name, dumpLeaf("choice"), dumpLeaf("value"))
+}
+
+var dumpMessage = dumpList("message",
+	dumpLeaf("transactionId"),
+	dumpLeaf("groupNo"),
+	dumpLeaf("abortOnError"),
+	&dumpField{name: "messageBody", fields: []*dumpField{dumpLeaf("tag"), dumpLeaf("data")}, choice: map[uint64]*dumpField{
+		MESSAGE_OPEN_REQUEST: dumpList("openRequest", dumpLeaf("codepage"), dumpLeaf("clientId"),
+			dumpLeaf("reqFileId"), dumpLeaf("serverId"), dumpLeaf("username"), dumpLeaf("password"),
+			dumpLeaf("smlVersion")),
+		MESSAGE_OPEN_RESPONSE: dumpList("openResponse", dumpLeaf("codepage"), dumpLeaf("clientId"),
+			dumpLeaf("reqFileId"), dumpLeaf("serverId"), dumpTime("refTime"), dumpLeaf("smlVersion")),
+		MESSAGE_CLOSE_REQUEST:  dumpList("closeRequest", dumpLeaf("globalSignature")),
+		MESSAGE_CLOSE_RESPONSE: dumpList("closeResponse", dumpLeaf("globalSignature")),
+		MESSAGE_GET_PROFILE_LIST_RESPONSE: dumpList("getProfileListResponse", dumpLeaf("serverId"),
+			dumpTime("actTime"), dumpLeaf("regPeriod"), dumpLeaf("parameterTreePath"), dumpTime("valTime"),
+			dumpLeaf("status"),
+			&dumpField{name: "periodList", each: dumpList("periodEntry", dumpLeaf("objName"), dumpLeaf("unit"),
+				dumpLeaf("scaler"), dumpLeaf("value"), dumpLeaf("valueSignature"))},
+			dumpLeaf("rawdata"), dumpLeaf("periodSignature")),
+		MESSAGE_GET_LIST_REQUEST: dumpList("getListRequest", dumpLeaf("clientId"), dumpLeaf("serverId"),
+			dumpLeaf("username"), dumpLeaf("password"), dumpLeaf("listName")),
+		MESSAGE_GET_LIST_RESPONSE: dumpList("getListResponse", dumpLeaf("clientId"), dumpLeaf("serverId"),
+			dumpLeaf("listName"), dumpTime("actSensorTime"),
+			&dumpField{name: "valList", each: dumpList("valListEntry", dumpLeaf("objName"), dumpLeaf("status"),
+				dumpTime("valTime"), dumpLeaf("unit"), dumpLeaf("scaler"), dumpLeaf("value"),
+				dumpLeaf("valueSignature"))},
+			dumpLeaf("listSignature"), dumpTime("actGatewayTime")),
+		MESSAGE_ATTENTION_RESPONSE: dumpList("attentionResponse", dumpLeaf("serverId"), dumpLeaf("attentionNo"),
+			dumpLeaf("attentionMsg"), dumpLeaf("attentionDetails")),
+	}},
+	&dumpField{name: "crc16", crc: true},
+	dumpLeaf("endOfSmlMsg"),
+)
+
+// child returns the description of the i-th element of a list, tag being the value of its first
+// element
+func (f *dumpField) child(i int, tag uint64) *dumpField {
+	switch {
+	case f == nil:
+		return nil
+	case f.each != nil:
+		return f.each
+	case f.choice != nil && i == 1:
+		return f.choice[tag]
+	case i < len(f.fields):
+		return f.fields[i]
+	}
+	return nil
+}
+
+type dumper struct {
+	sb       strings.Builder
+	buf      *Buffer
+	printed  int
+	msgStart int
+}
+
+// AnnotatedDump renders frame as hex dump annotated with the SML structure, one element per line
+// with its offset, its bytes and its field name and value, e.g. for reports about meters that
+// can't be parsed. frame may be a complete SML file including begin and end sequences or a payload
+// as passed to ParsePayload. Offsets of files containing escaped escape sequences refer to the
+// unescaped file. If parsing fails, the error is marked and the remaining bytes are dumped
+// unannotated.
+func AnnotatedDump(frame []byte) string {
+	d := &dumper{buf: &Buffer{Bytes: frame}}
+
+	if !bytes.HasPrefix(frame, startSeq) {
+		d.payload()
+		return d.sb.String()
+	}
+
+	n, escaped, complete, err := scanRest(frame[8:])
+	if !complete || err != nil {
+		d.buf.Cursor = 8
+		d.line(0, 0, "begin sequence")
+		if err == nil {
+			err = fmt.Errorf("end sequence missing")
+		}
+		if d.payload() {
+			d.fail(err)
+		}
+		return d.sb.String()
+	}
+
+	wire := frame[:8+n]
+	file := wire
+	if len(escaped) > 0 {
+		file = unescape(append([]byte(nil), wire...), escaped)
+	}
+	d.buf = &Buffer{Bytes: file[:len(file)-8], Cursor: 8}
+	d.line(0, 0, "begin sequence")
+	if d.payload() {
+		d.buf.Bytes = file
+		d.buf.Cursor = len(file)
+		crc := crc16Calculate(wire[:len(wire)-2], len(wire)-2)
+		d.line(len(file)-8, 0, fmt.Sprintf("end sequence, padding %d, crc16 %s",
+			file[len(file)-3], crcStatus(uint16(file[len(file)-2])<<8|uint16(file[len(file)-1]), crc)))
+	}
+	return d.sb.String()
+}
+
+// payload annotates the messages and padding up to the end of the buffer and reports whether they
+// could be parsed
+func (d *dumper) payload() (ok bool) {
+	buf := d.buf
+	for buf.Cursor < len(buf.Bytes) {
+		start := buf.Cursor
+		if buf.GetCurrentByte() == OCTET_MESSAGE_END {
+			for buf.Cursor < len(buf.Bytes) && buf.GetCurrentByte() == OCTET_MESSAGE_END {
+				buf.UpdateBytesRead(1)
+			}
+			d.line(start, 0, "padding")
+			continue
+		}
+		d.msgStart = start
+		if _, err := d.value(dumpMessage, 0); err != nil {
+			d.fail(err)
+			return false
+		}
+	}
+	return true
+}
+
+// value annotates the next element described by f and returns its value if it is a number
+func (d *dumper) value(f *dumpField, depth int) (num uint64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unexpected end of data at offset %d", d.buf.Cursor)
+		}
+	}()
+
+	buf := d.buf
+	name := "element"
+	if f != nil {
+		name = f.name
+	}
+
+	start := buf.Cursor
+	if start >= len(buf.Bytes) {
+		return 0, fmt.Errorf("unexpected end of data at offset %d", start)
+	}
+
+	switch buf.GetCurrentByte() {
+	case OCTET_MESSAGE_END:
+		buf.UpdateBytesRead(1)
+		d.line(start, depth, name+": end of message")
+		return 0, nil
+	case OCTET_OPTIONAL_SKIPPED:
+		buf.UpdateBytesRead(1)
+		d.line(start, depth, name+": skipped")
+		return 0, nil
+	}
+
+	typeField := buf.GetNextType()
+	switch typeField {
+	case OCTET_TYPE_LIST:
+		n := buf.GetNextLength()
+		d.line(start, depth, fmt.Sprintf("%s: list of %d", name, n))
+		var tag uint64
+		for i := 0; i < n; i++ {
+			v, err := d.value(f.child(i, tag), depth+1)
+			if err != nil {
+				return 0, err
+			}
+			if i == 0 {
+				tag = v
+			}
+		}
+		return 0, nil
+	case OCTET_TYPE_OCTET_STRING, OCTET_TYPE_BOOLEAN, OCTET_TYPE_INTEGER, OCTET_TYPE_UNSIGNED:
+	default:
+		return 0, fmt.Errorf("%w %02x at offset %d", ErrReservedType, typeField, start)
+	}
+
+	length := buf.GetNextLength()
+	if length < 0 || buf.Cursor+length > len(buf.Bytes) {
+		return 0, fmt.Errorf("invalid length %d at offset %d", length, buf.Cursor)
+	}
+	data := buf.Bytes[buf.Cursor : buf.Cursor+length]
+	buf.UpdateBytesRead(length)
+
+	for _, b := range data {
+		num = num<<8 | uint64(b)
+	}
+
+	var text string
+	switch typeField {
+	case OCTET_TYPE_OCTET_STRING:
+		text = fmt.Sprintf("%s: octet string[%d]", name, length)
+		if name == "objName" && length == 6 {
+			text += " " + obisString(data)
+		} else if printable(data) {
+			text += fmt.Sprintf(" %q", data)
+		}
+	case OCTET_TYPE_BOOLEAN:
+		text = fmt.Sprintf("%s: boolean %t", name, num > 0)
+	case OCTET_TYPE_INTEGER:
+		v := int64(num)
+		if length > 0 && length < 8 && num&(1<<(8*length-1)) != 0 {
+			v = int64(num | ^uint64(0)<<(8*length))
+		}
+		text = fmt.Sprintf("%s: integer %d", name, v)
+	case OCTET_TYPE_UNSIGNED:
+		text = fmt.Sprintf("%s: unsigned %d", name, num)
+		if f != nil && f.crc {
+			crc := crc16Calculate(buf.Bytes[d.msgStart:start], start-d.msgStart)
+			text = fmt.Sprintf("%s: %s", name, crcStatus(uint16(num), crc))
+		}
+	}
+	d.line(start, depth, text)
+
+	return num, nil
+}
+
+// line writes the bytes from start up to the cursor, 16 per line, annotating the first line
+func (d *dumper) line(start, depth int, text string) {
+	data := d.buf.Bytes[start:d.buf.Cursor]
+	indent := strings.Repeat("  ", depth)
+	for first := true; first || len(data) > 0; first = false {
+		n := len(data)
+		if n > 16 {
+			n = 16
+		}
+		if first {
+			fmt.Fprintf(&d.sb, "%04x  %-47s  %s%s\n", start, fmt.Sprintf("% x", data[:n]), indent, text)
+		} else {
+			fmt.Fprintf(&d.sb, "%04x  % x\n", start, data[:n])
+		}
+		start += n
+		data = data[n:]
+	}
+	d.printed = d.buf.Cursor
+}
+
+// fail marks err and writes the bytes that haven't been annotated yet
+func (d *dumper) fail(err error) {
+	fmt.Fprintf(&d.sb, "%04x  %-47s  ^ parse error: %v\n", d.printed, "", err)
+	d.buf.Cursor = len(d.buf.Bytes)
+	if d.printed < d.buf.Cursor {
+		d.line(d.printed, 0, "unparsed")
+	}
+}
+
+func crcStatus(crc, expected uint16) string {
+	if crc == expected {
+		return fmt.Sprintf("%04x (ok)", crc)
+	}
+	return fmt.Sprintf("%04x (expected %04x)", crc, expected)
+}
+
+// printable reports whether data is non-empty printable ASCII
+func printable(data []byte) bool {
+	for _, b := range data {
+		if b < 0x20 || b > 0x7e {
+			return false
+		}
+	}
+	return len(data) > 0
+}
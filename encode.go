@@ -0,0 +1,323 @@
+package gosml
+
+import (
+	"bytes"
+	"fmt"
+)
+
+var startSeq = []byte{0x1b, 0x1b, 0x1b, 0x1b, 0x01, 0x01, 0x01, 0x01}
+
+// appendTL appends a type-length field. For lists length is the number of elements, for all other
+// types the number of data bytes which is increased by the size of the TL field itself.
+func appendTL(b []byte, typ uint8, length int) []byte {
+	tlBytes := 1
+	if typ == OCTET_TYPE_LIST {
+		for length >= 1<<(4*tlBytes) {
+			tlBytes++
+		}
+	} else {
+		for length+tlBytes >= 1<<(4*tlBytes) {
+			tlBytes++
+		}
+		length += tlBytes
+	}
+
+	for i := tlBytes - 1; i >= 0; i-- {
+		tl := uint8(length>>(4*i)) & OCTET_LENGTH_FIELD
+		if i == tlBytes-1 {
+			tl |= typ
+		}
+		if i > 0 {
+			tl |= OCTET_ANOTHER_TL
+		}
+		b = append(b, tl)
+	}
+	return b
+}
+
+func appendOctetString(b []byte, s OctetString) []byte {
+	if s == nil {
+		return append(b, OCTET_OPTIONAL_SKIPPED)
+	}
+	b = appendTL(b, OCTET_TYPE_OCTET_STRING, len(s))
+	return append(b, s...)
+}
+
+func appendNumber(b []byte, typ uint8, size int, num uint64) []byte {
+	b = appendTL(b, typ, size)
+	for i := size - 1; i >= 0; i-- {
+		b = append(b, byte(num>>(8*i)))
+	}
+	return b
+}
+
+func appendU8(b []byte, num uint8) []byte {
+	return appendNumber(b, OCTET_TYPE_UNSIGNED, TYPE_NUMBER_8, uint64(num))
+}
+
+func appendU16(b []byte, num uint16) []byte {
+	return appendNumber(b, OCTET_TYPE_UNSIGNED, TYPE_NUMBER_16, uint64(num))
+}
+
+func appendU32(b []byte, num uint32) []byte {
+	return appendNumber(b, OCTET_TYPE_UNSIGNED, TYPE_NUMBER_32, uint64(num))
+}
+
+func appendI8(b []byte, num int8) []byte {
+	return appendNumber(b, OCTET_TYPE_INTEGER, TYPE_NUMBER_8, uint64(num))
+}
+
+func appendI16(b []byte, num int16) []byte {
+	return appendNumber(b, OCTET_TYPE_INTEGER, TYPE_NUMBER_16, uint64(num))
+}
+
+// appendTime appends an SML time of the given kind. Local timestamps are encoded with the whole
+// offset as local offset and a season time offset of 0.
+func appendTime(b []byte, t Time, kind TimeKind, offset int) []byte {
+	switch kind {
+	case TIME_KIND_NONE, TIME_KIND_UNKNOWN:
+		// times of unknown kind were skipped while parsing
+		return append(b, OCTET_OPTIONAL_SKIPPED)
+	case TIME_KIND_LOCAL_TIMESTAMP:
+		b = appendTL(b, OCTET_TYPE_LIST, 2)
+		b = appendU8(b, uint8(kind))
+		b = appendTL(b, OCTET_TYPE_LIST, 3)
+		b = appendU32(b, uint32(t))
+		b = appendI16(b, int16(offset))
+		return appendI16(b, 0)
+	}
+	b = appendTL(b, OCTET_TYPE_LIST, 2)
+	b = appendU8(b, uint8(kind))
+	return appendU32(b, uint32(t))
+}
+
+// appendValue appends a value using its raw data bytes if present, so numbers keep their width
+func appendValue(b []byte, v Value) ([]byte, error) {
+	typ := v.Typ & OCTET_TYPE_FIELD
+	switch {
+	case v.IsCompound():
+		elems, err := countElements(v.Raw)
+		if err != nil {
+			return nil, err
+		}
+		b = appendTL(b, OCTET_TYPE_LIST, elems)
+		return append(b, v.Raw...), nil
+	case v.Raw != nil:
+		b = appendTL(b, typ, len(v.Raw))
+		return append(b, v.Raw...), nil
+	case typ == OCTET_TYPE_OCTET_STRING:
+		return appendOctetString(b, v.DataBytes), nil
+	case typ == OCTET_TYPE_BOOLEAN:
+		b = appendTL(b, OCTET_TYPE_BOOLEAN, 1)
+		if v.DataBoolean {
+			return append(b, 0x01), nil
+		}
+		return append(b, 0x00), nil
+	case typ == OCTET_TYPE_INTEGER || typ == OCTET_TYPE_UNSIGNED:
+		return appendNumber(b, typ, v.Width(), uint64(v.DataInt)), nil
+	}
+	return nil, fmt.Errorf("unsupported value type %02x", v.Typ)
+}
+
+// countElements returns the number of elements in data
+func countElements(data []byte) (int, error) {
+	buf := &Buffer{Bytes: data}
+	n := 0
+	for buf.Cursor < len(data) {
+		if err := buf.skipElement(); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func appendListEntry(b []byte, le *ListEntry) ([]byte, error) {
+	b = appendTL(b, OCTET_TYPE_LIST, 7)
+	b = appendOctetString(b, le.ObjName)
+	if le.hasStatus {
+		b = appendTL(b, OCTET_TYPE_UNSIGNED, len(le.statusRaw))
+		b = append(b, le.statusRaw...)
+	} else {
+		b = append(b, OCTET_OPTIONAL_SKIPPED)
+	}
+	b = appendTime(b, le.valTime, le.valTimeKind, le.valTimeOffset)
+	if le.hasUnit {
+		b = appendU8(b, le.Unit)
+	} else {
+		b = append(b, OCTET_OPTIONAL_SKIPPED)
+	}
+	if le.hasScaler {
+		b = appendI8(b, le.scaler)
+	} else {
+		b = append(b, OCTET_OPTIONAL_SKIPPED)
+	}
+	var err error
+	if le.Value.Typ == OCTET_TYPE_OCTET_STRING && le.Value.DataBytes == nil && le.Value.Raw == nil {
+		b = append(b, OCTET_OPTIONAL_SKIPPED)
+	} else if b, err = appendValue(b, le.Value); err != nil {
+		return nil, fmt.Errorf("%s: %w", le.ObjectName(), err)
+	}
+	return appendOctetString(b, le.ValueSignature), nil
+}
+
+func appendGetListResponse(b []byte, msg GetListResponse) ([]byte, error) {
+	b = appendTL(b, OCTET_TYPE_LIST, 7)
+	b = appendOctetString(b, msg.ClientID)
+	b = appendOctetString(b, msg.ServerID)
+	b = appendOctetString(b, msg.ListName)
+	b = appendTime(b, msg.ActSensorTime, msg.actSensorTimeKind, msg.actSensorTimeOffset)
+	if msg.ValList == nil {
+		b = append(b, OCTET_OPTIONAL_SKIPPED)
+	} else {
+		b = appendTL(b, OCTET_TYPE_LIST, len(msg.ValList))
+		for _, le := range msg.ValList {
+			var err error
+			if b, err = appendListEntry(b, le); err != nil {
+				return nil, err
+			}
+		}
+	}
+	b = appendOctetString(b, msg.ListSignature)
+	return appendTime(b, msg.ActGatewayTime, msg.actGatewayTimeKind, msg.actGatewayOffset), nil
+}
+
+func appendOpenResponse(b []byte, msg OpenResponse) []byte {
+	b = appendTL(b, OCTET_TYPE_LIST, 6)
+	b = appendOctetString(b, msg.Codepage)
+	b = appendOctetString(b, msg.ClientID)
+	b = appendOctetString(b, msg.ReqFileID)
+	b = appendOctetString(b, msg.ServerID)
+	b = appendTime(b, msg.RefTime, msg.refTimeKind, msg.refTimeOffset)
+	if msg.Version == 0 {
+		return append(b, OCTET_OPTIONAL_SKIPPED)
+	}
+	return appendU8(b, msg.Version)
+}
+
+func appendAttentionResponse(b []byte, msg AttentionResponse) ([]byte, error) {
+	if msg.AttentionDetails != nil {
+		return nil, fmt.Errorf("attention details can't be encoded")
+	}
+	b = appendTL(b, OCTET_TYPE_LIST, 4)
+	b = appendOctetString(b, msg.ServerID)
+	b = appendOctetString(b, msg.AttentionNumber)
+	b = appendOctetString(b, msg.AttentionMessage)
+	return append(b, OCTET_OPTIONAL_SKIPPED), nil
+}
+
+func appendOpenRequest(b []byte, msg OpenRequest) []byte {
+	b = appendTL(b, OCTET_TYPE_LIST, 7)
+	b = appendOctetString(b, msg.Codepage)
+	b = appendOctetString(b, msg.ClientID)
+	b = appendOctetString(b, msg.ReqFileID)
+	b = appendOctetString(b, msg.ServerID)
+	b = appendOctetString(b, msg.Username)
+	b = appendOctetString(b, msg.Password)
+	if msg.Version == 0 {
+		return append(b, OCTET_OPTIONAL_SKIPPED)
+	}
+	return appendU8(b, msg.Version)
+}
+
+func appendCloseRequest(b []byte, msg CloseRequest) []byte {
+	b = appendTL(b, OCTET_TYPE_LIST, 1)
+	return appendOctetString(b, msg.GlobalSignature)
+}
+
+func appendGetListRequest(b []byte, msg GetListRequest) []byte {
+	b = appendTL(b, OCTET_TYPE_LIST, 5)
+	b = appendOctetString(b, msg.ClientID)
+	b = appendOctetString(b, msg.ServerID)
+	b = appendOctetString(b, msg.Username)
+	b = appendOctetString(b, msg.Password)
+	return appendOctetString(b, msg.ListName)
+}
+
+// EncodeMessage encodes a message including its CRC. Supported message bodies are OpenRequest,
+// OpenResponse, CloseRequest, CloseResponse, GetListRequest, GetListResponse and AttentionResponse
+// without details.
+//
+// Parsed messages are re-encoded losslessly except for the following normalizations: numbers other
+// than values and status words are encoded with their nominal width, e.g. a message tag sent in 2
+// bytes is encoded in 4; list entries are encoded with all 7 fields; status words are encoded as
+// unsigned; local timestamps carry their whole offset as local offset; and empty octet strings are
+// indistinguishable from skipped ones.
+func EncodeMessage(msg *Message) ([]byte, error) {
+	b := appendTL(nil, OCTET_TYPE_LIST, 6)
+	b = appendOctetString(b, msg.TransactionID)
+	b = appendU8(b, msg.GroupID)
+	b = appendU8(b, msg.AbortOnError)
+
+	b = appendTL(b, OCTET_TYPE_LIST, 2)
+	b = appendU32(b, msg.MessageBody.Tag)
+	switch data := msg.MessageBody.Data.(type) {
+	case OpenRequest:
+		b = appendOpenRequest(b, data)
+	case OpenResponse:
+		b = appendOpenResponse(b, data)
+	case CloseRequest:
+		b = appendCloseRequest(b, data)
+	case CloseResponse:
+		b = appendCloseRequest(b, CloseRequest(data))
+	case GetListRequest:
+		b = appendGetListRequest(b, data)
+	case GetListResponse:
+		var err error
+		if b, err = appendGetListResponse(b, data); err != nil {
+			return nil, err
+		}
+	case AttentionResponse:
+		var err error
+		if b, err = appendAttentionResponse(b, data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported message body %T", data)
+	}
+
+	b = appendU16(b, crc16Calculate(b, len(b)))
+	return append(b, OCTET_MESSAGE_END), nil
+}
+
+// EncodeFile encodes the given messages into a complete SML file including escaped begin and end
+// sequences, padding and CRC. Escape sequences within the messages are escaped by doubling them.
+func EncodeFile(messages ...*Message) ([]byte, error) {
+	var payload []byte
+	for _, msg := range messages {
+		msgBytes, err := EncodeMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		payload = append(payload, msgBytes...)
+	}
+
+	b := append([]byte(nil), startSeq...)
+	b = appendEscaped(b, payload)
+
+	padding := (4 - len(b)%4) % 4
+	for i := 0; i < padding; i++ {
+		b = append(b, 0x00)
+	}
+
+	b = append(b, endSeq...)
+	b = append(b, byte(padding))
+	crc := crc16Calculate(b, len(b))
+	return append(b, byte(crc>>8), byte(crc)), nil
+}
+
+// appendEscaped appends payload, doubling every escape sequence at a 4 byte aligned offset as the
+// reader only looks for escape sequences there
+func appendEscaped(b []byte, payload []byte) []byte {
+	for i := 0; i < len(payload); i += 4 {
+		end := i + 4
+		if end > len(payload) {
+			end = len(payload)
+		}
+		b = append(b, payload[i:end]...)
+		if bytes.Equal(payload[i:end], escSeq) {
+			b = append(b, escSeq...)
+		}
+	}
+	return b
+}
@@ -0,0 +1,71 @@
+package gosml
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// PowerFromEnergy derives the average power in W between two readings of the same energy register
+// (e.g. 1.8.0) taken dt apart. Both entries need to be numeric with the unit Wh. A decreasing
+// register is reported as error, as it indicates a rollover or a meter replacement.
+func PowerFromEnergy(prev, cur *ListEntry, dt time.Duration) (float64, error) {
+	if !bytes.Equal(prev.ObjName, cur.ObjName) {
+		return 0, fmt.Errorf("obis codes differ: %s and %s", prev.ObjectName(), cur.ObjectName())
+	}
+	if _, _, _, d, _, _, ok := cur.ObisFields(); !ok || d != 8 {
+		return 0, fmt.Errorf("%s is not an energy register", cur.ObjectName())
+	}
+	for _, le := range []*ListEntry{prev, cur} {
+		if !le.IsNumeric() {
+			return 0, fmt.Errorf("%s has non-numeric value", le.ObjectName())
+		}
+		if le.Unit != UNIT_WATT_HOUR {
+			return 0, fmt.Errorf("%s has unit %q (expected Wh)", le.ObjectName(), le.UnitString())
+		}
+	}
+	if dt <= 0 {
+		return 0, fmt.Errorf("invalid interval %v", dt)
+	}
+
+	delta := cur.Float() - prev.Float()
+	if delta < 0 {
+		return 0, fmt.Errorf("%s decreased by %g Wh", cur.ObjectName(), -delta)
+	}
+	return delta / dt.Hours(), nil
+}
+
+// MaxDemand returns the value of maximum demand registers (OBIS D-field 6, e.g. 1-0:1.6.0) along
+// with the time the maximum was captured, taken from the entry's valTime. The time is zero if the
+// meter doesn't send a (local) timestamp. ok is false for other entries.
+func (le *ListEntry) MaxDemand() (value float64, at time.Time, ok bool) {
+	if _, _, _, d, _, _, isObis := le.ObisFields(); !isObis || d != 6 || !le.IsNumeric() {
+		return 0, time.Time{}, false
+	}
+	at, _ = le.ValTime()
+	return le.Float(), at, true
+}
+
+// NetEnergy returns the net energy consumption of a prosumer, i.e. the total imported energy
+// (1-0:1.8.0) minus the total exported energy (1-0:2.8.0), scaled and in the unit of both registers.
+// ok is false if either register is missing or non-numeric or their units differ.
+func NetEnergy(list *GetListResponse) (net float64, ok bool) {
+	imported := findRegister(list, 1, 8, 0)
+	exported := findRegister(list, 2, 8, 0)
+	if imported == nil || exported == nil || imported.Unit != exported.Unit {
+		return 0, false
+	}
+	return imported.Float() - exported.Float(), true
+}
+
+// findRegister returns the first numeric electricity entry of the list with the given OBIS groups
+// C, D and E, or nil
+func findRegister(list *GetListResponse, c, d, e byte) *ListEntry {
+	for _, elem := range list.ValList {
+		ea, _, ec, ed, ee, _, ok := elem.ObisFields()
+		if ok && ea == 1 && ec == c && ed == d && ee == e && elem.IsNumeric() {
+			return elem
+		}
+	}
+	return nil
+}
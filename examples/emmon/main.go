@@ -2,31 +2,55 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"time"
 
 	sml "github.com/petesahatt/gosml"
+	"github.com/petesahatt/gosml/smlcsv"
 )
 
+// modes maps the subcommands to the functions handling a single input file
+var modes = map[string]func(r *bufio.Reader) error{
+	"print":    printReadings,
+	"json":     printJSON,
+	"csv":      printCSV,
+	"dump":     printDump,
+	"discover": discover,
+}
+
 func printUsage() {
-	fmt.Printf("Usage: %s [FILE]...\n", os.Args[0])
-	fmt.Println("  Reads FILE(s) and outputs found electricity meter readings (obis 1.8.0)")
+	fmt.Printf("Usage: %s [MODE] [FILE]...\n", os.Args[0])
+	fmt.Println("  Reads FILE(s) and outputs found electricity meter readings")
+	fmt.Println()
+	fmt.Println("Modes:")
+	fmt.Println("  print     print all readings (default)")
+	fmt.Println("  json      print a JSON array of readings per list")
+	fmt.Println("  csv       print readings as CSV rows timestamp,obis,value,unit")
+	fmt.Println("  dump      print an annotated hex dump of every SML file, e.g. for bug reports")
+	fmt.Println("  discover  list the OBIS codes sent in the first SML file with readings")
 }
 
 func main() {
-	// Check if at least one argument is given
-	if len(os.Args) < 2 {
-		printUsage()
-		os.Exit(1)
+	args := os.Args[1:]
+	mode := printReadings
+	if len(args) > 0 {
+		if m, ok := modes[args[0]]; ok {
+			mode = m
+			args = args[1:]
+		}
 	}
 
-	// define a handling callback function that get's called for matching obis list entries
-	handleFunc := func(message *sml.ListEntry) {
-		fmt.Printf("%s %s\n", message.ObjectName(), message.ValueString())
+	// Check if at least one file is given
+	if len(args) < 1 {
+		printUsage()
+		os.Exit(1)
 	}
 
-	// Go through all arguments
-	for _, filePath := range os.Args[1:] {
+	// Go through all files
+	for _, filePath := range args {
 		// check if argument is a valid file path
 		if _, err := os.Stat(filePath); os.IsNotExist(err) {
 			fmt.Printf("Error: File '%s' does not exist\n\n", filePath)
@@ -38,10 +62,76 @@ func main() {
 		if err != nil {
 			panic(err)
 		}
-		// create a buffered reader for the file
-		r := bufio.NewReader(f)
-		// read the file using gosml module with the option
-		// to call handleFunc for list entries that match the 1-1:1.8.0 obis code
-		sml.Read(r, sml.WithObisCallback(sml.OctetString{}, handleFunc))
+		// create a buffered reader for the file and handle it
+		err = mode(bufio.NewReader(f))
+		f.Close()
+		if err != nil {
+			fmt.Printf("Error: %s: %v\n", filePath, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// printReadings prints all list entries
+func printReadings(r *bufio.Reader) error {
+	// define a handling callback function that get's called for matching obis list entries
+	handleFunc := func(message *sml.ListEntry) {
+		fmt.Printf("%s %s\n", message.ObjectName(), message.ValueString())
+	}
+
+	// read the file using gosml module with the option
+	// to call handleFunc for all list entries
+	return sml.Read(r, sml.WithObisCallback(sml.OctetString{}, handleFunc))
+}
+
+// printJSON prints the entries of every GetListResponse as JSON array, using ListEntry.MarshalJSON
+func printJSON(r *bufio.Reader) error {
+	enc := json.NewEncoder(os.Stdout)
+	var encErr error
+	err := sml.Read(r, sml.WithGetListResponseCallback(func(msg sml.GetListResponse) {
+		if encErr == nil {
+			encErr = enc.Encode(msg.ValList)
+		}
+	}))
+	if err != nil {
+		return err
+	}
+	return encErr
+}
+
+// printCSV prints all numeric readings as CSV rows, using the time they are read as timestamp
+func printCSV(r *bufio.Reader) error {
+	w := smlcsv.NewWriter(os.Stdout)
+	writeErr := w.WriteHeader()
+	err := sml.Read(r, sml.WithObisCallback(sml.OctetString{}, func(le *sml.ListEntry) {
+		if writeErr == nil && le.IsNumeric() {
+			writeErr = w.Write(le, time.Now())
+		}
+	}))
+	if err != nil {
+		return err
+	}
+	return writeErr
+}
+
+// printDump prints an annotated dump of every completely read SML file, including files that
+// can't be parsed
+func printDump(r *bufio.Reader) error {
+	return sml.Read(r, sml.WithRawFrameCallback(func(payload []byte) {
+		fmt.Println(sml.AnnotatedDump(payload))
+	}))
+}
+
+// discover lists the OBIS codes of the first SML file with readings along with their values, which
+// helps to find the codes to register callbacks for
+func discover(r *bufio.Reader) error {
+	readings, err := sml.ReadFrame(r)
+	if err != nil {
+		return err
+	}
+	sort.Slice(readings, func(i, j int) bool { return readings[i].Obis < readings[j].Obis })
+	for _, reading := range readings {
+		fmt.Printf("%-20s %16g %s\n", reading.Obis, reading.Value, reading.Unit)
 	}
+	return nil
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	sml "github.com/petesahatt/gosml"
+)
+
+func printUsage() {
+	fmt.Printf("Usage: %s INPUT OUTPUT\n", os.Args[0])
+	fmt.Println("  Copies the valid SML files of the capture INPUT to OUTPUT, dropping partial files and garbage")
+}
+
+func main() {
+	// Check if input and output are given
+	if len(os.Args) != 3 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(os.Args[1])
+	if err != nil {
+		fmt.Printf("Error: %v\n\n", err)
+		printUsage()
+		os.Exit(1)
+	}
+
+	// extract the complete files with valid checksums
+	files := sml.ExtractFiles(data)
+
+	var out []byte
+	for _, file := range files {
+		out = append(out, file...)
+	}
+	if err := os.WriteFile(os.Args[2], out, 0666); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d files, %d of %d bytes kept\n", len(files), len(out), len(data))
+}
@@ -0,0 +1,10 @@
+package gosml
+
+// Fixtures exposes the meter captures of testdata_test.go to the external tests. The HOLLEY capture
+// is left out as its files exceed maxFileSize.
+var Fixtures = map[string][]byte{
+	"DZG":   fixtureDZG,
+	"EMH":   fixtureEMH,
+	"ISKRA": fixtureISKRA,
+	"ITRON": fixtureITRON,
+}
@@ -0,0 +1,46 @@
+package gosml
+
+import (
+	"bytes"
+)
+
+// ExtractFiles returns the complete SML files contained in data, e.g. a capture of a serial line,
+// in their escaped wire format. Only files with a valid checksum whose messages can all be parsed
+// are returned; partial files and garbage in between are dropped. Writing the files back to back
+// yields a clean capture, e.g. for a test fixture. The returned files reference data.
+func ExtractFiles(data []byte) [][]byte {
+	var files [][]byte
+	for {
+		start := bytes.Index(data, startSeq)
+		if start < 0 {
+			return files
+		}
+		window := data[start+8:]
+		n, escaped, complete, err := scanRest(window)
+		if !complete {
+			return files
+		}
+		wire := data[start : start+8+n]
+		if err != nil || !validFileCrc(wire) {
+			// the begin sequence of the next file may be part of the rejected bytes, e.g. if this
+			// file is truncated, so continue searching right after this begin sequence
+			data = window
+			continue
+		}
+		data = window[n:]
+		fileBytes := wire
+		if len(escaped) > 0 {
+			fileBytes = unescape(append([]byte(nil), wire...), escaped)
+		}
+		if _, err := parseFrame(fileBytes); err != nil {
+			continue
+		}
+		files = append(files, wire)
+	}
+}
+
+// validFileCrc verifies the checksum at the end of an SML file in its escaped wire format
+func validFileCrc(wire []byte) bool {
+	crc := crc16Calculate(wire[:len(wire)-2], len(wire)-2)
+	return wire[len(wire)-2] == byte(crc>>8) && wire[len(wire)-1] == byte(crc)
+}
@@ -0,0 +1,72 @@
+package gosml
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ParseFrames decodes frames split by an upstream stage, e.g. a hardware deframer or another
+// process, and emits their messages in order. A frame is either a complete SML file including begin
+// and end sequences or a payload as passed to ParsePayload. The options are applied like in
+// ParsePayload, i.e. filters and callbacks take effect and only messages passing the filters are
+// emitted. Errors reported for a frame, including frames that can't be deframed, are sent on the
+// error channel as well as to the error callback.
+//
+// Both channels must be drained by the caller. They are closed once frames is closed, or after
+// ErrTooManyErrors has been sent if the budget set by WithMaxConsecutiveErrors is exhausted.
+func ParseFrames(frames <-chan []byte, opts ...ReadOption) (<-chan *Message, <-chan error) {
+	messages := make(chan *Message)
+	errs := make(chan error)
+
+	o := newOptions(opts)
+	o.messageCallback = func(msg *Message) {
+		messages <- msg
+	}
+	errorCallback := o.errorCallback
+	o.errorCallback = func(err error) {
+		if errorCallback != nil {
+			errorCallback(err)
+		}
+		errs <- err
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(messages)
+		for frame := range frames {
+			payload, err := deframe(frame)
+			if err == nil {
+				err = o.handlePayload(payload)
+			} else {
+				err = o.fileFailed(err)
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	return messages, errs
+}
+
+// deframe returns the payload of frame if it is a complete SML file, with escape sequences
+// unescaped, or frame itself if it doesn't start with the begin sequence
+func deframe(frame []byte) ([]byte, error) {
+	if !bytes.HasPrefix(frame, startSeq) {
+		return frame, nil
+	}
+	n, escaped, complete, err := scanRest(frame[8:])
+	switch {
+	case !complete:
+		return nil, fmt.Errorf("end sequence missing in frame of %d bytes", len(frame))
+	case err != nil:
+		return nil, err
+	case 8+n != len(frame):
+		return nil, fmt.Errorf("%d bytes after end sequence", len(frame)-8-n)
+	}
+	if len(escaped) > 0 {
+		frame = unescape(append([]byte(nil), frame...), escaped)
+	}
+	return frame[8 : len(frame)-8], nil
+}
@@ -0,0 +1,77 @@
+package gosml
+
+import (
+	"sync"
+	"time"
+)
+
+// FreezeEvent reports a cumulative register that stopped advancing, see FreezeDetector
+type FreezeEvent struct {
+	Obis   string
+	Value  float64
+	Since  time.Time // time the value was first read
+	Frames int       // number of readings since then without change
+}
+
+// FreezeDetector watches cumulative registers, e.g. 1.8.0, and reports registers whose value
+// doesn't change across readings although the meter keeps sending, which may indicate a faulty
+// meter. It is safe for concurrent use.
+type FreezeDetector struct {
+	mu       sync.Mutex
+	frames   int
+	duration time.Duration
+	onFreeze func(FreezeEvent)
+	states   map[string]*freezeState
+}
+
+type freezeState struct {
+	value    int64
+	since    time.Time
+	frames   int
+	reported bool
+}
+
+// NewFreezeDetector returns a detector calling onFreeze once a register's value hasn't changed for
+// at least the given number of readings and duration. A zero frames or duration disables that
+// criterion. onFreeze is called once per freeze, again only after the value changed.
+func NewFreezeDetector(frames int, duration time.Duration, onFreeze func(FreezeEvent)) *FreezeDetector {
+	return &FreezeDetector{
+		frames:   frames,
+		duration: duration,
+		onFreeze: onFreeze,
+		states:   map[string]*freezeState{},
+	}
+}
+
+// Update feeds the detector with le read now. It can be passed to WithObisCallback directly.
+func (fd *FreezeDetector) Update(le *ListEntry) {
+	fd.Observe(le, time.Now())
+}
+
+// Observe feeds the detector with le read at t. Entries with non-numeric values or OBIS codes
+// shorter than 6 bytes are ignored.
+func (fd *FreezeDetector) Observe(le *ListEntry, t time.Time) {
+	if len(le.ObjName) < 6 || !le.IsNumeric() {
+		return
+	}
+	obis := le.ObjectName()
+
+	fd.mu.Lock()
+	state, ok := fd.states[obis]
+	if !ok || state.value != le.Value.DataInt {
+		fd.states[obis] = &freezeState{value: le.Value.DataInt, since: t}
+		fd.mu.Unlock()
+		return
+	}
+	state.frames++
+	frozen := !state.reported && state.frames >= fd.frames && t.Sub(state.since) >= fd.duration
+	if frozen {
+		state.reported = true
+	}
+	event := FreezeEvent{Obis: obis, Value: le.Float(), Since: state.since, Frames: state.frames}
+	fd.mu.Unlock()
+
+	if frozen && fd.onFreeze != nil {
+		fd.onFreeze(event)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"time"
 )
 
 const (
@@ -24,6 +26,28 @@ var ErrUnrecognizedSequence = errors.New("unrecognized sequence")
 // end of sequence has been detected.
 var ErrSequenceTooLong = errors.New("max sequence length exceeded")
 
+// ErrLimitExceeded means that a file exceeds a limit set by WithMaxListEntries or WithMaxValueLen.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// ErrNoBeginSequence is reported when WithMaxConsecutiveErrors is set and maxFileSize bytes were
+// discarded without finding the begin sequence of a file, e.g. because of a wrong baud rate.
+var ErrNoBeginSequence = errors.New("no begin sequence found")
+
+// ErrTooManyErrors is returned by Read when more consecutive files than allowed by
+// WithMaxConsecutiveErrors failed, e.g. because the serial port is configured with a wrong baud rate.
+var ErrTooManyErrors = errors.New("too many consecutive errors")
+
+// SkippedBytesError is reported to the error callback when bytes had to be discarded before the
+// start sequence of an SML file was found, e.g. when attaching to a running stream mid-file.
+// Unusually high counts may indicate a baud rate mismatch.
+type SkippedBytesError struct {
+	Count int
+}
+
+func (e *SkippedBytesError) Error() string {
+	return fmt.Sprintf("skipped %d bytes before start sequence", e.Count)
+}
+
 type OctetString []byte
 
 type Time uint32
@@ -33,71 +57,267 @@ type Value struct {
 	DataBytes   OctetString
 	DataBoolean bool
 	DataInt     int64
+	Raw         OctetString // data bytes as sent, without type-length field
 }
 
-func readChunk(r *bufio.Reader, buf []byte) error {
-	_, err := io.ReadFull(r, buf)
-	return err
+// Width returns the size in bytes of the type of numeric values, or 0 for other types. Encoded
+// lengths of 1 to 8 bytes are rounded up to the next type, so the width is 1, 2, 4 or 8, e.g. 8 for
+// the 6 byte values of many energy registers.
+func (v Value) Width() int {
+	switch v.Typ & OCTET_TYPE_FIELD {
+	case OCTET_TYPE_INTEGER, OCTET_TYPE_UNSIGNED:
+		return int(v.Typ & OCTET_LENGTH_FIELD)
+	}
+	return 0
+}
+
+// IsCompound reports whether the value is a list, e.g. a COSEM value carrying its own scaler and
+// unit. Compound values aren't decoded, their elements are kept in Raw.
+func (v Value) IsCompound() bool {
+	return v.Typ == OCTET_TYPE_LIST
+}
+
+// Equal reports whether both values have the same type and data
+func (v Value) Equal(other Value) bool {
+	return v.Typ == other.Typ && v.DataInt == other.DataInt && v.DataBoolean == other.DataBoolean &&
+		bytes.Equal(v.DataBytes, other.DataBytes) && (!v.IsCompound() || bytes.Equal(v.Raw, other.Raw))
+}
+
+// readChunk fills buf from r. A file truncated by the end of the stream (e.g. a device unplugged
+// mid-frame) is treated as the clean end of the stream, so io.EOF is returned whether or not
+// parts of the chunk could be read. n is the number of bytes read.
+func readChunk(r *bufio.Reader, buf []byte) (n int, err error) {
+	n, err = io.ReadFull(r, buf)
+	if err == io.ErrUnexpectedEOF {
+		return n, io.EOF
+	}
+	return n, err
 }
 
 // readFile reads from buffered reader until next SML file has been completely read and returns
 // full SML file as byte slice which can then be parsed with FileParse to get its messages
 func readFile(r *bufio.Reader) ([]byte, error) {
-	buf := make([]byte, maxFileSize)
+	fileBytes, _, err := readFileSkipped(r)
+	return fileBytes, err
+}
+
+// readFileSkipped works like readFile but additionally returns the number of bytes that were
+// discarded before the start sequence was found
+func readFileSkipped(r *bufio.Reader) ([]byte, int, error) {
+	fileBytes, skipped, _, err := readFileCounted(r, 0)
+	return fileBytes, skipped, err
+}
+
+// readFileCounted works like readFileSkipped but additionally returns the total number of bytes
+// consumed from r, including the skipped ones. maxSkip limits the bytes skipped, see readStartCounted.
+func readFileCounted(r *bufio.Reader, maxSkip int) (fileBytes []byte, skipped int, consumed int, err error) {
+	skipped, consumed, err = readStartCounted(r, maxSkip)
+	if err != nil {
+		return nil, skipped, consumed, err
+	}
+
+	if fileBytes, n, ok, err := readRestBuffered(r); ok {
+		return fileBytes, skipped, consumed + n, err
+	}
+
+	fileBytes, read, err := readRestCounted(r)
+	return fileBytes, skipped, consumed + read, err
+}
+
+// Detect reports whether the begin sequence of an SML file appears within the next bytes of the
+// buffered reader, up to the maximum file size or the reader's buffer size if smaller. It returns
+// as soon as the begin sequence has been read, so it doesn't wait for more data than necessary. No
+// bytes are consumed, so the reader can be passed to Read afterwards. If the stream ends before the
+// begin sequence has been found, the read error is returned along with false.
+func Detect(r *bufio.Reader) (bool, error) {
+	n := maxFileSize
+	if r.Size() < n {
+		n = r.Size()
+	}
+	for {
+		window, _ := r.Peek(r.Buffered())
+		if bytes.Contains(window, startSeq) {
+			return true, nil
+		}
+		if len(window) >= n {
+			return false, nil
+		}
+		// wait for at least one more byte
+		if _, err := r.Peek(len(window) + 1); err != nil {
+			window, _ = r.Peek(r.Buffered())
+			return bytes.Contains(window, startSeq), err
+		}
+	}
+}
 
+// readStartCounted reads from buffered reader until the begin sequence of an SML file has been
+// consumed and returns the number of bytes that were discarded before and the number of bytes
+// consumed. If maxSkip > 0 it returns ErrNoBeginSequence once maxSkip bytes have been discarded.
+func readStartCounted(r *bufio.Reader, maxSkip int) (skipped int, read int, err error) {
 	var len int
-	var err error
 
 	// find escape sequence/begin 1B 1B 1B 1B 01 01 01 01
 	for len < 8 {
-		if buf[len], err = r.ReadByte(); err != nil {
-			return nil, err
+		if maxSkip > 0 && len == 0 && read >= maxSkip {
+			return read, read, ErrNoBeginSequence
 		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return read - len, read, err
+		}
+		read++
 
-		if (buf[len] == 0x1b && len < 4) || (buf[len] == 0x01 && len >= 4) {
+		switch {
+		case (b == 0x1b && len < 4) || (b == 0x01 && len >= 4):
 			len++
-		} else {
+		case b == 0x1b && len == 4:
+			// the preceding escape byte didn't belong to the sequence
+		case b == 0x1b:
+			len = 1
+		default:
 			len = 0
 		}
 	}
 
-	// found start sequence
-	for len+8 < maxFileSize {
-		if err = readChunk(r, buf[len:len+4]); err != nil {
-			return nil, err
+	return read - len, read, nil
+}
+
+// readRestBuffered scans the data already buffered by r for the end sequence of the SML file whose
+// begin sequence has just been read. This avoids reading the file in 4 byte chunks. If the buffered
+// data doesn't suffice to decide whether the file is complete, nothing is consumed and ok is false.
+// Otherwise n is the number of bytes consumed.
+func readRestBuffered(r *bufio.Reader) (fileBytes []byte, n int, ok bool, err error) {
+	window, _ := r.Peek(r.Buffered())
+
+	n, escaped, complete, err := scanRest(window)
+	if !complete {
+		return nil, 0, false, nil
+	}
+	if err == nil {
+		fileBytes = make([]byte, 8+n)
+		copy(fileBytes, startSeq)
+		copy(fileBytes[8:], window[:n])
+		fileBytes = unescape(fileBytes, escaped)
+	}
+	if _, discardErr := r.Discard(n); err == nil {
+		err = discardErr
+	}
+	return fileBytes, n, true, err
+}
+
+// scanRest scans window, the data following the begin sequence of an SML file, for the end sequence
+// in steps of 4 bytes like readRestCounted. n is the number of bytes of window belonging to the file
+// including the end sequence, or the number of bytes to discard along with ErrUnrecognizedSequence
+// or ErrSequenceTooLong. escaped holds the offsets of doubled escape sequences within window.
+// complete is false if window ends before this could be decided.
+func scanRest(window []byte) (n int, escaped []int, complete bool, err error) {
+	for 8+n+8 < maxFileSize {
+		if n+4 > len(window) {
+			return 0, nil, false, nil
+		}
+
+		// find escape sequence
+		if bytes.Equal(window[n:n+4], escSeq) {
+			if n+8 > len(window) {
+				return 0, nil, false, nil
+			}
+
+			if bytes.Equal(window[n+4:n+8], escSeq) {
+				// escaped escape sequence within the payload
+				escaped = append(escaped, n+4)
+				n += 8
+				continue
+			}
+
+			if window[n+4] != 0x1a {
+				// don't read other escaped sequences yet
+				return n + 8, nil, true, ErrUnrecognizedSequence
+			}
+
+			// found end sequence
+			return n + 8, escaped, true, nil
+		}
+
+		n += 4
+	}
+
+	return n, nil, true, ErrSequenceTooLong
+}
+
+// unescape removes the doubled escape sequences at the given offsets relative to the end of the
+// begin sequence from fileBytes. fileBytes is modified in place.
+func unescape(fileBytes []byte, escaped []int) []byte {
+	if len(escaped) == 0 {
+		return fileBytes
+	}
+	out := fileBytes[:8]
+	last := 8
+	for _, offset := range escaped {
+		out = append(out, fileBytes[last:8+offset]...)
+		last = 8 + offset + 4
+	}
+	return append(out, fileBytes[last:]...)
+}
+
+// readRestCounted reads the SML file whose begin sequence has just been read in chunks of 4 bytes
+// and returns it along with the number of bytes consumed. An escape sequence within the payload is
+// escaped by doubling it, the duplicate is dropped.
+func readRestCounted(r *bufio.Reader) (fileBytes []byte, consumed int, err error) {
+	buf := make([]byte, maxFileSize)
+	copy(buf, startSeq)
+
+	len := 8
+	read := 8
+	for read+8 < maxFileSize {
+		n, err := readChunk(r, buf[len:len+4])
+		consumed += n
+		if err != nil {
+			return nil, consumed, err
 		}
+		read += 4
 
 		// find escape sequence
 		if bytes.Equal(buf[len:len+4], escSeq) {
 			len += 4
 
 			// read end sequence
-			if err = readChunk(r, buf[len:len+4]); err != nil {
-				return nil, err
+			n, err = readChunk(r, buf[len:len+4])
+			consumed += n
+			if err != nil {
+				return nil, consumed, err
+			}
+			read += 4
+
+			if bytes.Equal(buf[len:len+4], escSeq) {
+				// escaped escape sequence within the payload, keep one of them
+				continue
 			}
 
 			if buf[len] == 0x1a {
 				// found end sequence
 				len += 4
-				return buf[:len], nil
+				return buf[:len], consumed, nil
 			}
 
 			// don't read other escaped sequences yet
-			return nil, ErrUnrecognizedSequence
+			return nil, consumed, ErrUnrecognizedSequence
 		}
 
 		// continue reading
 		len += 4
 	}
 
-	return nil, ErrSequenceTooLong
+	return nil, consumed, ErrSequenceTooLong
 }
 
-// parseFile parses SML file provided as byte slice
-func parseFile(fileBytes []byte) ([]*Message, error) {
+// parseFileWith parses SML file provided as byte slice, applying the filter and limits of config.
+// Errors are prefixed with the index of the message and the path of the field that failed, e.g.
+// "message 1: valList: entry 3: scaler: ...".
+func parseFileWith(fileBytes []byte, config parseConfig) ([]*Message, error) {
 	buf := &Buffer{
-		Bytes:  fileBytes,
-		Cursor: 0,
+		Bytes:       fileBytes,
+		Cursor:      0,
+		parseConfig: config,
 	}
 
 	messages := make([]*Message, 0)
@@ -111,7 +331,7 @@ func parseFile(fileBytes []byte) ([]*Message, error) {
 
 		msg, err := MessageParse(buf, true)
 		if err != nil {
-			return messages, err
+			return messages, fmt.Errorf("message %d: %w", len(messages), err)
 		}
 
 		messages = append(messages, msg)
@@ -120,9 +340,50 @@ func parseFile(fileBytes []byte) ([]*Message, error) {
 	return messages, nil
 }
 
+// parseFrame parses a complete SML file as returned by readFile
+func parseFrame(fileBytes []byte) (msgs []*Message, err error) {
+	// parse without escaped begin and end sequences
+	return ParseMessages(fileBytes[8 : len(fileBytes)-8])
+}
+
+// ParseMessages parses the messages of an SML file whose begin and end sequences have already been
+// removed and whose escape sequences have been unescaped, e.g. by another transport. Trailing
+// padding is ignored. Parser panics caused by malformed data are converted to errors.
+func ParseMessages(payload []byte) (msgs []*Message, err error) {
+	return parseMessages(payload, parseConfig{})
+}
+
+// parseMessages works like ParseMessages but applies the filter and limits of config
+func parseMessages(payload []byte, config parseConfig) (msgs []*Message, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("parse panic")
+		}
+	}()
+	return parseFileWith(payload, config)
+}
+
+// findEntries returns all list entries of the given messages whose OBIS code starts with prefix
+func findEntries(messages []*Message, prefix OctetString) []*ListEntry {
+	var entries []*ListEntry
+	for _, msg := range messages {
+		list, ok := msg.MessageBody.Data.(GetListResponse)
+		if !ok {
+			continue
+		}
+		for _, elem := range list.ValList {
+			if len(elem.ObjName) > 0 && bytes.HasPrefix(elem.ObjName, prefix) {
+				entries = append(entries, elem)
+			}
+		}
+	}
+	return entries
+}
+
 type obisGroupCallback struct {
-	callbacks   []func(message *ListEntry)
-	childGroups map[byte]*obisGroupCallback
+	callbacks     []func(message *ListEntry)
+	childGroups   map[byte]*obisGroupCallback
+	wildcardGroup *obisGroupCallback
 }
 
 func newObisGroupCallback() *obisGroupCallback {
@@ -144,27 +405,403 @@ func (oc *obisGroupCallback) addCallback(subCode OctetString, callback func(mess
 	}
 }
 
-func (oc *obisGroupCallback) call(obisCode OctetString, listEntry *ListEntry) {
+func (oc *obisGroupCallback) addPatternCallback(pattern ObisPattern, callback func(message *ListEntry)) {
+	if len(pattern) == 0 {
+		oc.callbacks = append(oc.callbacks, callback)
+		return
+	}
+	if pattern[0] == OBIS_WILDCARD {
+		if oc.wildcardGroup == nil {
+			oc.wildcardGroup = newObisGroupCallback()
+		}
+		oc.wildcardGroup.addPatternCallback(pattern[1:], callback)
+		return
+	}
+	group := byte(pattern[0])
+	subGroupCallback, ok := oc.childGroups[group]
+	if !ok {
+		subGroupCallback = newObisGroupCallback()
+		oc.childGroups[group] = subGroupCallback
+	}
+	subGroupCallback.addPatternCallback(pattern[1:], callback)
+}
+
+// call calls the callbacks registered for all prefixes of obisCode and reports whether a callback
+// registered below oc, i.e. for a non-empty prefix, has been called
+func (oc *obisGroupCallback) call(obisCode OctetString, listEntry *ListEntry) (matched bool) {
 	// call registered callbacks
 	for _, callback := range oc.callbacks {
 		callback(listEntry)
 	}
 	// check if additional registered handlers exist for remaining obis groups
 	if len(obisCode) == 0 {
-		return
+		return false
 	}
 	subOc, ok := oc.childGroups[obisCode[0]]
 	if ok {
-		subOc.call(obisCode[1:], listEntry)
+		matched = subOc.call(obisCode[1:], listEntry) || len(subOc.callbacks) > 0
+	}
+	if oc.wildcardGroup != nil {
+		matched = oc.wildcardGroup.call(obisCode[1:], listEntry) || len(oc.wildcardGroup.callbacks) > 0 || matched
+	}
+	return matched
+}
+
+// wants reports whether a callback is registered for a prefix of obisCode
+func (oc *obisGroupCallback) wants(obisCode OctetString) bool {
+	if len(oc.callbacks) > 0 {
+		return true
+	}
+	if len(obisCode) == 0 {
+		return false
 	}
+	if subOc, ok := oc.childGroups[obisCode[0]]; ok && subOc.wants(obisCode[1:]) {
+		return true
+	}
+	return oc.wildcardGroup != nil && oc.wildcardGroup.wants(obisCode[1:])
+}
+
+type obisTransform struct {
+	obisCode  OctetString
+	transform func(float64) float64
+}
+
+type scalerOverride struct {
+	obisCode OctetString
+	exp      int8
+}
+
+type obisCallbackAll struct {
+	obisCode OctetString
+	callback func(entries []*ListEntry)
 }
 
 type options struct {
 	topLevelCallback *obisGroupCallback
+	fallback         func(message *ListEntry)
+	allCallbacks     []obisCallbackAll
+	errorCallback    func(err error)
+	rawFrameCallback func(frame []byte)
+	sanityCheck      bool
+	serverIDFilter   OctetString
+	listNameFilter   OctetString
+	obisAllowlist    []OctetString
+	obisDenylist     []OctetString
+	dedupe           bool
+	lastValues       map[string]Value
+	transforms       []obisTransform
+	scalers          []scalerOverride
+	decoders         []obisDecoder
+	deadline         time.Time
+	now              func() time.Time // clock of the deadline and sampling, replaced in tests
+	sampleInterval   time.Duration
+	lastSample       time.Time
+	maxErrors        int
+	location         *time.Location
+	stats            ReadStats
+	parseWorkers     int
+	consecutiveErrs  int
+	selective        bool
+	maxListEntries   int
+	maxValueLen      int
+	trace            io.Writer
+	crcWarn          bool
+	shortEntries     bool
+
+	openResponseCallback      func(msg OpenResponse)
+	closeResponseCallback     func(msg CloseResponse)
+	getListResponseCallback   func(msg GetListResponse)
+	attentionResponseCallback func(msg AttentionResponse)
+	profileListCallback       func(msg GetProfileListResponse)
+	messageCallback           func(msg *Message) // see ParseFrames
+}
+
+// acceptEntry reports whether a list entry is passed on to the callbacks
+func (o *options) acceptEntry(le *ListEntry) bool {
+	if o.obisAllowlist != nil && !hasObisPrefix(le.ObjName, o.obisAllowlist) {
+		return false
+	}
+	if hasObisPrefix(le.ObjName, o.obisDenylist) {
+		return false
+	}
+	if o.sanityCheck {
+		if err := checkEntry(le); err != nil {
+			o.reportError(&EntryError{Entry: le, Err: err})
+			return false
+		}
+	}
+	return true
+}
+
+// hasObisPrefix reports whether obisCode starts with any of the given prefixes
+func hasObisPrefix(obisCode OctetString, prefixes []OctetString) bool {
+	for _, prefix := range prefixes {
+		if bytes.HasPrefix(obisCode, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptList reports whether a GetListResponse is passed on to the callbacks
+func (o *options) acceptList(list *GetListResponse) bool {
+	if o.serverIDFilter != nil && !bytes.HasPrefix(list.ServerID, o.serverIDFilter) {
+		return false
+	}
+	if o.listNameFilter != nil && !bytes.HasPrefix(list.ListName, o.listNameFilter) {
+		return false
+	}
+	return true
+}
+
+// filterMessages removes GetListResponses that aren't accepted and removes list entries that aren't
+// accepted from the remaining ones
+func (o *options) filterMessages(messages []*Message) []*Message {
+	filtered := messages[:0]
+	for _, msg := range messages {
+		list, ok := msg.MessageBody.Data.(GetListResponse)
+		if !ok {
+			filtered = append(filtered, msg)
+			continue
+		}
+		if !o.acceptList(&list) {
+			continue
+		}
+		entries := make([]*ListEntry, 0, len(list.ValList))
+		for _, elem := range list.ValList {
+			o.overrideScaler(elem)
+			if o.acceptEntry(elem) {
+				o.applyTransforms(elem)
+				o.applyDecoder(elem)
+				elem.location = o.location
+				entries = append(entries, elem)
+			}
+		}
+		list.ValList = entries
+		list.location = o.location
+		msg.MessageBody.Data = list
+		filtered = append(filtered, msg)
+	}
+	return filtered
+}
+
+// applyTransforms sets the transform of a list entry to the composition of all transforms whose
+// OBIS code prefixes the entry's, in the order they were registered
+func (o *options) applyTransforms(le *ListEntry) {
+	for _, t := range o.transforms {
+		if !bytes.HasPrefix(le.ObjName, t.obisCode) {
+			continue
+		}
+		if prev := le.transform; prev != nil {
+			next := t.transform
+			le.transform = func(v float64) float64 { return next(prev(v)) }
+		} else {
+			le.transform = t.transform
+		}
+	}
+}
+
+// overrideScaler sets the scaler of a list entry to the exponent of the last scaler override whose
+// OBIS code prefixes the entry's
+func (o *options) overrideScaler(le *ListEntry) {
+	for _, so := range o.scalers {
+		if bytes.HasPrefix(le.ObjName, so.obisCode) {
+			le.scaler = so.exp
+			le.hasScaler = true
+		}
+	}
+}
+
+// sample reports whether the callbacks are called for the file just read, i.e. whether the sample
+// interval has elapsed since the last file delivered
+func (o *options) sample() bool {
+	if o.sampleInterval <= 0 {
+		return true
+	}
+	now := o.now()
+	if !o.lastSample.IsZero() && now.Sub(o.lastSample) < o.sampleInterval {
+		return false
+	}
+	o.lastSample = now
+	return true
+}
+
+// changed reports whether the value of a list entry differs from the one last delivered for its
+// OBIS code and remembers it. Without WithDedupe every entry counts as changed.
+func (o *options) changed(le *ListEntry) bool {
+	if !o.dedupe {
+		return true
+	}
+	key := string(le.ObjName)
+	if last, ok := o.lastValues[key]; ok && last.Equal(le.Value) {
+		return false
+	}
+	if o.lastValues == nil {
+		o.lastValues = map[string]Value{}
+	}
+	o.lastValues[key] = le.Value
+	return true
+}
+
+// dispatch calls the callback registered for the message's type
+func (o *options) dispatch(msg *Message) {
+	switch data := msg.MessageBody.Data.(type) {
+	case OpenResponse:
+		if o.openResponseCallback != nil {
+			o.openResponseCallback(data)
+		}
+	case CloseResponse:
+		if o.closeResponseCallback != nil {
+			o.closeResponseCallback(data)
+		}
+	case GetListResponse:
+		if o.getListResponseCallback != nil {
+			o.getListResponseCallback(data)
+		}
+	case AttentionResponse:
+		if o.attentionResponseCallback != nil {
+			o.attentionResponseCallback(data)
+		}
+	case GetProfileListResponse:
+		if o.profileListCallback != nil {
+			o.profileListCallback(data)
+		}
+	}
+}
+
+func newOptions(opts []ReadOption) *options {
+	o := &options{now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) deadlinePassed() bool {
+	return !o.deadline.IsZero() && !o.now().Before(o.deadline)
+}
+
+// handleFile parses a complete SML file and calls the registered callbacks. It only returns an
+// error once the budget set by WithMaxConsecutiveErrors is exhausted.
+func (o *options) handleFile(fileBytes []byte) error {
+	return o.handlePayload(fileBytes[8 : len(fileBytes)-8])
+}
+
+// handlePayload works like handleFile for a payload without begin and end sequences
+func (o *options) handlePayload(payload []byte) error {
+	fileMessages, err := parseMessages(payload, o.parseConfig())
+	return o.handleMessages(payload, fileMessages, err)
+}
+
+// parseConfig returns the settings for parsing files. Without WithSelectiveParsing or along with a
+// fallback callback all list entries are parsed.
+func (o *options) parseConfig() parseConfig {
+	config := parseConfig{
+		maxListEntries: o.maxListEntries,
+		maxValueLen:    o.maxValueLen,
+		trace:          o.trace,
+		crcWarn:        o.crcWarn,
+		shortEntries:   o.shortEntries,
+	}
+	if o.selective && o.fallback == nil {
+		config.entryFilter = o.subscribed
+	}
+	return config
+}
+
+// subscribed reports whether an OBIS callback is registered for objName
+func (o *options) subscribed(objName OctetString) bool {
+	if o.topLevelCallback != nil && o.topLevelCallback.wants(objName) {
+		return true
+	}
+	for _, cb := range o.allCallbacks {
+		if bytes.HasPrefix(objName, cb.obisCode) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleMessages calls the registered callbacks for the messages parsed from payload
+func (o *options) handleMessages(payload []byte, fileMessages []*Message, err error) error {
+	if o.rawFrameCallback != nil {
+		o.rawFrameCallback(payload)
+	}
+	if err != nil {
+		return o.fileFailed(err)
+	}
+	o.consecutiveErrs = 0
+	o.stats.FramesParsed++
+	for i, msg := range fileMessages {
+		if msg.CrcUnverified {
+			o.reportError(fmt.Errorf("message %d: %w, kept unverified", i, ErrCrcMismatch))
+		}
+	}
+	if !o.sample() {
+		return nil
+	}
+	fileMessages = o.filterMessages(fileMessages)
+	for _, msg := range fileMessages {
+		if o.messageCallback != nil {
+			o.messageCallback(msg)
+		}
+		o.dispatch(msg)
+		if o.topLevelCallback != nil && msg.MessageBody.Tag == MESSAGE_GET_LIST_RESPONSE {
+			list, ok := msg.MessageBody.Data.(GetListResponse)
+			if !ok {
+				continue
+			}
+			for _, elem := range list.ValList {
+				if len(elem.ObjName) > 0 && o.changed(elem) {
+					if !o.topLevelCallback.call(elem.ObjName, elem) && o.fallback != nil {
+						o.fallback(elem)
+					}
+				}
+			}
+		}
+	}
+	for _, all := range o.allCallbacks {
+		if entries := findEntries(fileMessages, all.obisCode); len(entries) > 0 {
+			all.callback(entries)
+		}
+	}
+	return nil
+}
+
+// fileFailed reports the error of a file that couldn't be read or parsed and returns
+// ErrTooManyErrors once the budget set by WithMaxConsecutiveErrors is exhausted
+func (o *options) fileFailed(err error) error {
+	o.reportError(err)
+	o.stats.FramesSkipped++
+	o.consecutiveErrs++
+	if o.maxErrors > 0 && o.consecutiveErrs >= o.maxErrors {
+		return fmt.Errorf("%w: %d files failed, last: %v", ErrTooManyErrors, o.consecutiveErrs, err)
+	}
+	return nil
+}
+
+// maxSkip returns the number of bytes that may be skipped in search of a begin sequence before it
+// counts as a failed file. Without WithMaxConsecutiveErrors any number of bytes may be skipped.
+func (o *options) maxSkip() int {
+	if o.maxErrors > 0 {
+		return maxFileSize
+	}
+	return 0
+}
+
+func (o *options) reportError(err error) {
+	if o.errorCallback != nil {
+		o.errorCallback(err)
+	}
 }
 
 type ReadOption func(*options)
 
+// WithObisCallback registers a callback that is called for every list entry whose OBIS code
+// starts with obisCode. An empty obisCode matches all entries.
+// Note that a meter may report the same OBIS code more than once within one file (e.g. for
+// current and previous billing periods), in which case the callback is called for each of them.
+// Use WithObisCallbackAll to receive all occurrences at once.
 func WithObisCallback(obisCode OctetString, callback func(message *ListEntry)) ReadOption {
 	return func(o *options) {
 		if o.topLevelCallback == nil {
@@ -174,52 +811,400 @@ func WithObisCallback(obisCode OctetString, callback func(message *ListEntry)) R
 	}
 }
 
+// WithObisPatternCallback works like WithObisCallback but matches OBIS codes against pattern, which
+// may contain wildcard groups, e.g. the pattern of "1-0:1.8.*" matches all tariffs of 1.8.
+func WithObisPatternCallback(pattern ObisPattern, callback func(message *ListEntry)) ReadOption {
+	return func(o *options) {
+		if o.topLevelCallback == nil {
+			o.topLevelCallback = newObisGroupCallback()
+		}
+		o.topLevelCallback.addPatternCallback(pattern, callback)
+	}
+}
+
+// WithObisFallback registers a callback that is called for every list entry not matched by any
+// callback registered with WithObisCallback or WithObisPatternCallback. Callbacks registered for the
+// empty OBIS code match all entries and therefore don't count as match.
+func WithObisFallback(callback func(message *ListEntry)) ReadOption {
+	return func(o *options) {
+		if o.topLevelCallback == nil {
+			o.topLevelCallback = newObisGroupCallback()
+		}
+		o.fallback = callback
+	}
+}
+
+// WithTransform applies fn to the scaled values of all list entries whose OBIS code starts with
+// obisCode before they are passed to any callback, e.g. to correct for a current transformer ratio or
+// to calibrate a meter. The transformed value is returned by ListEntry.Float and ValueString while
+// Value.DataInt keeps the raw value. Several matching transforms are applied in registration order.
+func WithTransform(obisCode OctetString, fn func(float64) float64) ReadOption {
+	return func(o *options) {
+		o.transforms = append(o.transforms, obisTransform{obisCode: obisCode, transform: fn})
+	}
+}
+
+// WithScalerOverride replaces the scaler of all list entries whose OBIS code starts with obisCode by
+// exp, e.g. for meter firmware known to report a wrong scaler for a register. The override is applied
+// before the entries are checked and passed to any callback, so Scaler, Float and ValueString all use
+// it. If several overrides match, the one registered last wins.
+func WithScalerOverride(obisCode OctetString, exp int8) ReadOption {
+	return func(o *options) {
+		o.scalers = append(o.scalers, scalerOverride{obisCode: obisCode, exp: exp})
+	}
+}
+
+// WithObisCallbackAll registers a callback that is called once per SML file with all list entries
+// whose OBIS code starts with obisCode, in the order they appear in the file. The callback is not
+// called for files without matching entries.
+func WithObisCallbackAll(obisCode OctetString, callback func(entries []*ListEntry)) ReadOption {
+	return func(o *options) {
+		o.allCallbacks = append(o.allCallbacks, obisCallbackAll{obisCode: obisCode, callback: callback})
+	}
+}
+
+// WithErrorCallback registers a callback that is called for errors Read recovers from, e.g.
+// unrecognized or unparsable SML files and bytes skipped while searching for the next file
+// (reported as *SkippedBytesError).
+func WithErrorCallback(callback func(err error)) ReadOption {
+	return func(o *options) {
+		o.errorCallback = callback
+	}
+}
+
+// WithRawFrameCallback registers a callback that is called with the payload of every completely
+// read SML file (without escaped begin and end sequences) before it is parsed, regardless of
+// whether it can be parsed afterwards.
+func WithRawFrameCallback(callback func(frame []byte)) ReadOption {
+	return func(o *options) {
+		o.rawFrameCallback = callback
+	}
+}
+
+// WithOpenResponseCallback registers a callback that is called for every OpenResponse message
+func WithOpenResponseCallback(callback func(msg OpenResponse)) ReadOption {
+	return func(o *options) {
+		o.openResponseCallback = callback
+	}
+}
+
+// WithCloseResponseCallback registers a callback that is called for every CloseResponse message
+func WithCloseResponseCallback(callback func(msg CloseResponse)) ReadOption {
+	return func(o *options) {
+		o.closeResponseCallback = callback
+	}
+}
+
+// WithGetListResponseCallback registers a callback that is called for every GetListResponse
+// message before the OBIS callbacks of its entries are called
+func WithGetListResponseCallback(callback func(msg GetListResponse)) ReadOption {
+	return func(o *options) {
+		o.getListResponseCallback = callback
+	}
+}
+
+// WithAttentionResponseCallback registers a callback that is called for every AttentionResponse
+// message
+func WithAttentionResponseCallback(callback func(msg AttentionResponse)) ReadOption {
+	return func(o *options) {
+		o.attentionResponseCallback = callback
+	}
+}
+
+// WithGetProfileListResponseCallback registers a callback that is called for every
+// GetProfileListResponse message
+func WithGetProfileListResponseCallback(callback func(msg GetProfileListResponse)) ReadOption {
+	return func(o *options) {
+		o.profileListCallback = callback
+	}
+}
+
+// WithSanityCheck validates list entries against the type constraints of the SML specification,
+// e.g. that only numeric values carry a unit or scaler. Violating entries are reported to the error
+// callback as *EntryError and not passed on to any other callback.
+func WithSanityCheck() ReadOption {
+	return func(o *options) {
+		o.sanityCheck = true
+	}
+}
+
+// WithObisAllowlist removes all list entries whose OBIS code doesn't start with one of codes from
+// the GetListResponses before they are passed to any callback. Several allowlists are merged.
+func WithObisAllowlist(codes []OctetString) ReadOption {
+	return func(o *options) {
+		o.obisAllowlist = append(o.obisAllowlist, codes...)
+		if o.obisAllowlist == nil {
+			o.obisAllowlist = []OctetString{}
+		}
+	}
+}
+
+// WithObisDenylist removes all list entries whose OBIS code starts with one of codes from the
+// GetListResponses before they are passed to any callback. The denylist takes precedence over the
+// allowlist.
+func WithObisDenylist(codes []OctetString) ReadOption {
+	return func(o *options) {
+		o.obisDenylist = append(o.obisDenylist, codes...)
+	}
+}
+
+// WithServerIDFilter restricts all callbacks to GetListResponses whose server id starts with id.
+// This allows to read a single meter's data from a stream shared by several meters.
+func WithServerIDFilter(id OctetString) ReadOption {
+	return func(o *options) {
+		o.serverIDFilter = id
+	}
+}
+
+// WithListName restricts all callbacks to GetListResponses whose list name starts with name, e.g.
+// 1-0:99.1.0 for meters sending the same OBIS codes in a list of current values and in lists of past
+// billing periods. Lists without a name are dropped.
+func WithListName(name OctetString) ReadOption {
+	return func(o *options) {
+		o.listNameFilter = name
+	}
+}
+
+// WithSampleInterval limits callbacks to at most one SML file per interval d. Files read before the
+// interval since the last delivered file has elapsed are discarded, including their messages for the
+// message type callbacks.
+func WithSampleInterval(d time.Duration) ReadOption {
+	return func(o *options) {
+		o.sampleInterval = d
+	}
+}
+
+// WithMaxConsecutiveErrors makes Read return ErrTooManyErrors once n consecutive files couldn't be
+// read or parsed, so a stream that never yields a valid file fails fast. Every maxFileSize (512)
+// bytes discarded without finding a begin sequence count as a failed file too, see
+// ErrNoBeginSequence. The count is reset by every successfully parsed file. n <= 0 allows an
+// unlimited number of errors, which is the default.
+func WithMaxConsecutiveErrors(n int) ReadOption {
+	return func(o *options) {
+		o.maxErrors = n
+	}
+}
+
+// WithTimezone presents the times of the GetListResponses and list entries passed to the callbacks,
+// e.g. SensorTime and ValTime, in loc. By default timestamps are presented in UTC and local
+// timestamps in the zone given by their offset.
+func WithTimezone(loc *time.Location) ReadOption {
+	return func(o *options) {
+		o.location = loc
+	}
+}
+
+// WithSelectiveParsing makes Read parse only the list entries an OBIS callback is registered for,
+// i.e. by WithObisCallback, WithObisPatternCallback, WithObisCallbackAll or WithRegistry. Other
+// entries are skipped without decoding their fields, which saves allocations on meters sending many
+// registers when only a few are of interest. Skipped entries are missing from the GetListResponses
+// passed to the message callbacks. It has no effect along with WithObisFallback.
+func WithSelectiveParsing() ReadOption {
+	return func(o *options) {
+		o.selective = true
+	}
+}
+
+// WithMaxListEntries rejects files containing a list of more than n entries with an error wrapping
+// ErrLimitExceeded, which is passed to the error callback while the file is skipped. Along with
+// WithMaxValueLen this caps the resources spent on untrusted input, e.g. a gateway accepting
+// arbitrary TCP connections. n <= 0 allows any number of entries, which is the default.
+func WithMaxListEntries(n int) ReadOption {
+	return func(o *options) {
+		o.maxListEntries = n
+	}
+}
+
+// WithMaxValueLen rejects files containing a list entry whose value exceeds n bytes like
+// WithMaxListEntries. n <= 0 allows values of any size, which is the default.
+func WithMaxValueLen(n int) ReadOption {
+	return func(o *options) {
+		o.maxValueLen = n
+	}
+}
+
+// WithTrace writes a line per parse step to w, naming the parse function along with the offset and
+// TL byte it starts at, e.g. to find where parsing of a new meter's data diverges. Tracing is off by
+// default and costs nothing then. With WithParseWorkers files are parsed concurrently, so w needs to
+// be safe for concurrent use.
+func WithTrace(w io.Writer) ReadOption {
+	return func(o *options) {
+		o.trace = w
+	}
+}
+
+// WithCrcWarn keeps messages whose checksum doesn't match instead of skipping their file, e.g. to
+// still use the probably correct data of a noisy line. Such messages are marked CrcUnverified and an
+// error wrapping ErrCrcMismatch is reported to the error callback for each of them, so consumers can
+// decide whether to trust them. Note that OBIS callbacks receive their entries like any other.
+func WithCrcWarn() ReadOption {
+	return func(o *options) {
+		o.crcWarn = true
+	}
+}
+
+// WithShortListEntries reads list entries of 5 fields as objName, unit, scaler, value and
+// valueSignature, for meters omitting status and valTime instead of sending them as skipped
+// optionals. By default the fields of short entries are read in order, see ListEntryParse.
+func WithShortListEntries() ReadOption {
+	return func(o *options) {
+		o.shortEntries = true
+	}
+}
+
+// WithDedupe suppresses calls of OBIS callbacks for list entries whose value didn't change since
+// the previous entry delivered for the same OBIS code. The last values are kept per Read call and
+// don't carry over to subsequent calls.
+func WithDedupe() ReadOption {
+	return func(o *options) {
+		o.dedupe = true
+	}
+}
+
 // Read reads and parses sml file from given buffered reader.
 // If sml file is not recognized ErrUnrecognizedSequence is returned.
 // If sml file is too long ErrSequenceTooLong is returned.
 // If file is successfully read and parsed slice of found messages is returned
+//
+// Read processes the stream file by file: besides the buffer of r it holds at most one SML file of
+// up to 512 bytes at a time, so streams of arbitrary length, e.g. multi-gigabyte capture logs, are
+// read with constant memory. Options keeping state across files, e.g. WithSampleInterval and
+// WithMaxConsecutiveErrors, hold a fixed amount of it, except for WithDedupe and WithRegistry which
+// keep one value per distinct OBIS code.
 func Read(r *bufio.Reader, opts ...ReadOption) error {
-	options := &options{}
-	for _, opt := range opts {
-		opt(options)
+	_, err := ReadWithStats(r, opts...)
+	return err
+}
+
+// ReadStats describes the stream processed by ReadWithStats
+type ReadStats struct {
+	FramesParsed  int   // files parsed successfully
+	FramesSkipped int   // files that couldn't be read or parsed
+	BytesRead     int64 // bytes consumed from the reader, including skipped ones
+	EndedMidFrame bool  // the stream ended within a file rather than between files
+}
+
+// ReadWithStats works like Read but additionally returns statistics about the processed stream,
+// e.g. to monitor the health of a long-running collector
+func ReadWithStats(r *bufio.Reader, opts ...ReadOption) (ReadStats, error) {
+	options := newOptions(opts)
+	if options.parseWorkers > 1 {
+		return options.readParallel(r)
 	}
-loop:
-	for {
-		var fileBytes []byte
-		fileBytes, err := readFile(r)
-		switch {
-		case err == io.EOF:
-			break loop
-		case err == ErrSequenceTooLong || err == ErrUnrecognizedSequence:
-			continue
-		case err != nil:
-			return err
+	for !options.deadlinePassed() {
+		var file readResult
+		file.fileBytes, file.skipped, file.consumed, file.err = readFileCounted(r, options.maxSkip())
+		if done, err := options.handleRead(&file); done || err != nil {
+			return options.stats, err
 		}
-		// parse without escaped begin and end sequences
-		fileMessages, parseErr := func() (msgs []*Message, err error) {
-			defer func() {
-				if r := recover(); r != nil {
-					err = errors.New("parse panic")
-				}
-			}()
-			return parseFile(fileBytes[8 : len(fileBytes)-8])
-		}()
-		if parseErr != nil {
-			continue
+	}
+	return options.stats, nil
+}
+
+// readResult is the outcome of reading a single file from the stream
+type readResult struct {
+	fileBytes []byte
+	skipped   int
+	consumed  int
+	err       error
+
+	// set if the file has been parsed in advance by a parse worker, see WithParseWorkers
+	parsed   chan struct{}
+	messages []*Message
+	parseErr error
+}
+
+// handleRead handles the outcome of reading a file and reports whether the stream has ended
+func (o *options) handleRead(file *readResult) (done bool, err error) {
+	o.stats.BytesRead += int64(file.consumed)
+	if file.skipped > 0 {
+		o.reportError(&SkippedBytesError{Count: file.skipped})
+	}
+	switch {
+	case file.err == io.EOF:
+		o.stats.EndedMidFrame = file.consumed > file.skipped
+		return true, nil
+	case file.err == ErrSequenceTooLong || file.err == ErrUnrecognizedSequence || file.err == ErrNoBeginSequence:
+		return false, o.fileFailed(file.err)
+	case file.err != nil:
+		return true, file.err
+	}
+	if file.parsed == nil {
+		return false, o.handleFile(file.fileBytes)
+	}
+	<-file.parsed
+	return false, o.handleMessages(file.fileBytes[8:len(file.fileBytes)-8], file.messages, file.parseErr)
+}
+
+// ParseBytes parses the SML files contained in data and calls the callbacks registered by opts like
+// Read. This suits transports delivering complete files at once, e.g. MQTT or UDP payloads, and
+// batch processing of captures: data is scanned in place, so files are only copied if they contain
+// escaped escape sequences. Octet strings of the parsed messages reference data, which must not be
+// modified while they are in use.
+func ParseBytes(data []byte, opts ...ReadOption) error {
+	options := newOptions(opts)
+	for !options.deadlinePassed() {
+		start := bytes.Index(data, startSeq)
+		if start < 0 {
+			// like readStartCounted, a partial begin sequence at the end isn't counted as skipped
+			if skipped := len(data) - partialSuffix(data, startSeq); skipped > 0 {
+				options.reportError(&SkippedBytesError{Count: skipped})
+			}
+			return nil
 		}
-		for _, msg := range fileMessages {
-			if options.topLevelCallback != nil && msg.MessageBody.Tag == MESSAGE_GET_LIST_RESPONSE {
-				list, ok := msg.MessageBody.Data.(GetListResponse)
-				if !ok {
-					continue
-				}
-				for _, elem := range list.ValList {
-					if len(elem.ObjName) > 0 {
-						options.topLevelCallback.call(elem.ObjName, elem)
-					}
-				}
+		if start > 0 {
+			options.reportError(&SkippedBytesError{Count: start})
+		}
+
+		window := data[start+8:]
+		n, escaped, complete, err := scanRest(window)
+		if !complete {
+			// file truncated by the end of data
+			return nil
+		}
+		fileBytes := data[start : start+8+n]
+		data = window[n:]
+		if err != nil {
+			if err := options.fileFailed(err); err != nil {
+				return err
 			}
+			continue
+		}
+		if len(escaped) > 0 {
+			fileBytes = unescape(append([]byte(nil), fileBytes...), escaped)
+		}
+		if err := options.handleFile(fileBytes); err != nil {
+			return err
 		}
 	}
 	return nil
 }
+
+// ParsePayload works like ParseBytes for the payload of a single SML file whose begin and end
+// sequences have already been removed and whose escape sequences have been unescaped. This allows
+// transports wrapping SML differently, e.g. hybrid DSMR/P1 devices, to reuse the decoding and
+// callbacks of this package. Like in ParseBytes, parse errors are reported to the error callback;
+// use ParseMessages to receive them directly.
+func ParsePayload(payload []byte, opts ...ReadOption) error {
+	return newOptions(opts).handlePayload(payload)
+}
+
+// partialSuffix returns the length of the longest suffix of data that is a proper prefix of seq
+func partialSuffix(data, seq []byte) int {
+	for n := len(seq) - 1; n > 0; n-- {
+		if bytes.HasSuffix(data, seq[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// ReadUntil works like Read but stops once deadline has passed. The deadline is checked before
+// each SML file, so a file being read when the deadline passes is completed and its callbacks are
+// called. Reads blocking on r are not interrupted, use e.g. net.Conn.SetReadDeadline for that.
+func ReadUntil(r *bufio.Reader, deadline time.Time, opts ...ReadOption) error {
+	opts = append(opts, func(o *options) {
+		o.deadline = deadline
+	})
+	return Read(r, opts...)
+}
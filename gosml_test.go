@@ -3,11 +3,19 @@ package gosml
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 // ---------------------------------------------------------------------------
@@ -20,7 +28,7 @@ func TestReadChunk_Full(t *testing.T) {
 	data := []byte{0xAA, 0xBB, 0xCC, 0xDD}
 	r := bufio.NewReaderSize(iotest_oneByteReader(bytes.NewReader(data)), 1)
 	buf := make([]byte, 4)
-	if err := readChunk(r, buf); err != nil {
+	if _, err := readChunk(r, buf); err != nil {
 		t.Fatalf("readChunk returned error: %v", err)
 	}
 	if !bytes.Equal(buf, data) {
@@ -31,7 +39,7 @@ func TestReadChunk_Full(t *testing.T) {
 func TestReadChunk_EOF(t *testing.T) {
 	r := bufio.NewReader(bytes.NewReader(nil))
 	buf := make([]byte, 4)
-	err := readChunk(r, buf)
+	_, err := readChunk(r, buf)
 	if err == nil {
 		t.Fatal("expected error on empty reader")
 	}
@@ -254,12 +262,12 @@ func TestListEntryString(t *testing.T) {
 }
 
 // ---------------------------------------------------------------------------
-// Unit tests: parseFile panic recovery
+// Unit tests: parseFileWith panic recovery
 // ---------------------------------------------------------------------------
 
 func TestParseFilePanicRecovery(t *testing.T) {
-	// Corrupt data that will cause a panic inside parseFile.
-	// The Read() function wraps parseFile in a recover(), so it should
+	// Corrupt data that will cause a panic inside parseFileWith.
+	// The Read() function wraps parseFileWith in a recover(), so it should
 	// return nil error (it skips bad frames and continues).
 	corrupt := buildSMLFrame([]byte{0x76, 0xFF, 0xFF, 0xFF})
 	r := bufio.NewReader(bytes.NewReader(corrupt))
@@ -855,6 +863,4158 @@ func TestReadAllFixtures_FloatSanity(t *testing.T) {
 	}
 }
 
+// ---------------------------------------------------------------------------
+// Unit tests: UnitString
+// ---------------------------------------------------------------------------
+
+func TestUnitString(t *testing.T) {
+	for unit, want := range map[uint8]string{
+		UNIT_WATT_HOUR: "Wh",
+		UNIT_WATT:      "W",
+		UNIT_VOLT:      "V",
+		UNIT_AMPERE:    "A",
+		UNIT_HERTZ:     "Hz",
+		0:              "",
+		UNIT_COUNT:     "",
+	} {
+		le := &ListEntry{Unit: unit}
+		if got := le.UnitString(); got != want {
+			t.Errorf("UnitString(%d) = %q, want %q", unit, got, want)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ReadFrame
+// ---------------------------------------------------------------------------
+
+func TestReadFrame_DZG(t *testing.T) {
+	r := bufio.NewReader(bytes.NewReader(fixtureDZG))
+	readings, err := ReadFrame(r)
+	if err != nil {
+		t.Fatalf("ReadFrame error: %v", err)
+	}
+	var found bool
+	for _, rd := range readings {
+		if rd.Obis == "1-0:1.8.0*255" {
+			found = true
+			if rd.Unit != "Wh" {
+				t.Errorf("unit = %q, want Wh", rd.Unit)
+			}
+			if rd.Raw <= 0 || math.Abs(rd.Value-float64(rd.Raw)*0.1) > 1e-6 {
+				t.Errorf("unexpected value %f (raw %d)", rd.Value, rd.Raw)
+			}
+			if rd.Time.IsZero() {
+				t.Error("reading time not set")
+			}
+		}
+	}
+	if !found {
+		t.Fatal("missing 1-0:1.8.0*255 reading")
+	}
+}
+
+func TestReadFrame_EOF(t *testing.T) {
+	r := bufio.NewReader(bytes.NewReader(nil))
+	if _, err := ReadFrame(r); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: duplicate OBIS codes / WithObisCallbackAll
+// ---------------------------------------------------------------------------
+
+func TestObisCallbackAll_Duplicates(t *testing.T) {
+	obis := []byte{1, 0, 1, 8, 0, 255}
+	frame := buildSMLFrame(smlGetListResponse(
+		smlListEntry(obis, UNIT_WATT_HOUR, -1, 1000),
+		smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 5),
+		smlListEntry(obis, UNIT_WATT_HOUR, -1, 900),
+	))
+
+	var single []int64
+	var all [][]int64
+	r := bufio.NewReader(bytes.NewReader(frame))
+	err := Read(r,
+		WithObisCallback(OctetString(obis), func(le *ListEntry) {
+			single = append(single, le.Value.DataInt)
+		}),
+		WithObisCallbackAll(OctetString(obis), func(entries []*ListEntry) {
+			var values []int64
+			for _, le := range entries {
+				values = append(values, le.Value.DataInt)
+			}
+			all = append(all, values)
+		}),
+		WithObisCallbackAll(OctetString{1, 0, 2, 8, 0}, func(entries []*ListEntry) {
+			t.Error("callback called without matching entries")
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if len(single) != 2 {
+		t.Fatalf("WithObisCallback: expected 2 calls, got %d", len(single))
+	}
+	if len(all) != 1 {
+		t.Fatalf("WithObisCallbackAll: expected 1 call per file, got %d", len(all))
+	}
+	if len(all[0]) != 2 || all[0][0] != 1000 || all[0][1] != 900 {
+		t.Fatalf("WithObisCallbackAll: unexpected entries %v", all[0])
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: leading garbage / WithErrorCallback
+// ---------------------------------------------------------------------------
+
+func TestReadFileSkipped_LeadingGarbage(t *testing.T) {
+	garbage := []byte{0x01, 0x1b, 0x1b, 0x77, 0x01, 0x01, 0x42}
+	data := append(append(garbage, fixtureDZG...), fixtureDZG...)
+	r := bufio.NewReader(bytes.NewReader(data))
+	_, skipped, err := readFileSkipped(r)
+	if err != nil {
+		t.Fatalf("readFileSkipped error: %v", err)
+	}
+	if skipped != len(garbage) {
+		t.Fatalf("skipped = %d, want %d", skipped, len(garbage))
+	}
+	// the following file starts immediately
+	if _, skipped, err = readFileSkipped(r); err != nil || skipped != 0 {
+		t.Fatalf("second file: skipped = %d, %v, want 0, nil", skipped, err)
+	}
+}
+
+func TestReadErrorCallback_SkippedBytes(t *testing.T) {
+	data := append([]byte{0xde, 0xad, 0xbe, 0xef, 0x00}, fixtureDZG...)
+	r := bufio.NewReader(bytes.NewReader(data))
+	var skipped []int
+	err := Read(r, WithErrorCallback(func(err error) {
+		if se, ok := err.(*SkippedBytesError); ok {
+			skipped = append(skipped, se.Count)
+		}
+	}))
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if len(skipped) != 1 || skipped[0] != 5 {
+		t.Fatalf("expected one report of 5 skipped bytes, got %v", skipped)
+	}
+}
+
+func TestReadErrorCallback_ParseError(t *testing.T) {
+	corrupt := buildSMLFrame([]byte{0x76, 0xFF, 0xFF, 0xFF})
+	r := bufio.NewReader(bytes.NewReader(corrupt))
+	var errs []error
+	if err := Read(r, WithErrorCallback(func(err error) { errs = append(errs, err) })); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 reported error, got %v", errs)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ListEntry.Duration()
+// ---------------------------------------------------------------------------
+
+func TestDuration(t *testing.T) {
+	for _, tc := range []struct {
+		unit   uint8
+		scaler int8
+		value  int64
+		want   time.Duration
+	}{
+		{UNIT_SECOND, 0, 90, 90 * time.Second},
+		{UNIT_MINUTE, 0, 15, 15 * time.Minute},
+		{UNIT_HOUR, -1, 15, 90 * time.Minute},
+		{UNIT_DAY, 0, 2, 48 * time.Hour},
+	} {
+		le := &ListEntry{
+			Unit:   tc.unit,
+			scaler: tc.scaler,
+			Value:  Value{Typ: OCTET_TYPE_UNSIGNED | TYPE_NUMBER_32, DataInt: tc.value},
+		}
+		got, ok := le.Duration()
+		if !ok || got != tc.want {
+			t.Errorf("Duration() = %v, %v, want %v, true", got, ok, tc.want)
+		}
+	}
+}
+
+func TestDuration_NonTimeUnit(t *testing.T) {
+	le := &ListEntry{Unit: UNIT_WATT_HOUR, Value: Value{Typ: OCTET_TYPE_UNSIGNED | TYPE_NUMBER_32, DataInt: 1}}
+	if _, ok := le.Duration(); ok {
+		t.Fatal("Duration() should fail for Wh")
+	}
+	le = &ListEntry{Unit: UNIT_SECOND, Value: Value{Typ: OCTET_TYPE_OCTET_STRING}}
+	if _, ok := le.Duration(); ok {
+		t.Fatal("Duration() should fail for octet string value")
+	}
+	for _, v := range []int64{200000, -200000} {
+		le = &ListEntry{Unit: UNIT_DAY, Value: Value{Typ: OCTET_TYPE_INTEGER | TYPE_NUMBER_32, DataInt: v}}
+		if d, ok := le.Duration(); ok {
+			t.Fatalf("Duration() of %d days = %v, should fail as it overflows", v, d)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ListEntryParse entry shapes
+// ---------------------------------------------------------------------------
+
+func TestListEntryParse_SkippedStatusAndValTime(t *testing.T) {
+	entry := smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 1234)
+	buf := &Buffer{Bytes: append(entry, 0x00), Cursor: 0}
+	le, err := ListEntryParse(buf)
+	if err != nil {
+		t.Fatalf("ListEntryParse error: %v", err)
+	}
+	if le.Value.DataInt != 1234 || le.Unit != UNIT_WATT_HOUR || le.scaler != -1 {
+		t.Fatalf("unexpected entry %+v", le)
+	}
+	if buf.Cursor != len(entry) {
+		t.Fatalf("cursor = %d, want %d", buf.Cursor, len(entry))
+	}
+}
+
+func TestListEntryParse_FiveFields(t *testing.T) {
+	// objName, status, valTime, unit, scaler
+	entry := []byte{0x75, 0x07, 1, 0, 16, 7, 0, 255, 0x62, 0x08, 0x01, 0x62, UNIT_WATT, 0x52, 0xff}
+	buf := &Buffer{Bytes: append(entry, 0x00), Cursor: 0}
+	le, err := ListEntryParse(buf)
+	if err != nil {
+		t.Fatalf("ListEntryParse error: %v", err)
+	}
+	if status, ok := le.Status(); !ok || status != 8 || le.Unit != UNIT_WATT || le.scaler != -1 || le.Value.Typ != 0 {
+		t.Fatalf("unexpected entry %+v", le)
+	}
+	if buf.Cursor != len(entry) {
+		t.Fatalf("cursor = %d, want %d", buf.Cursor, len(entry))
+	}
+}
+
+func TestListEntryParse_ShortEntries(t *testing.T) {
+	// objName, unit, scaler, value, valueSignature
+	entry := []byte{0x75, 0x07, 1, 0, 16, 7, 0, 255, 0x62, UNIT_WATT, 0x52, 0x00, 0x53, 0x01, 0x00, 0x01}
+	buf := &Buffer{Bytes: append(entry, 0x00), Cursor: 0, parseConfig: parseConfig{shortEntries: true}}
+	le, err := ListEntryParse(buf)
+	if err != nil {
+		t.Fatalf("ListEntryParse error: %v", err)
+	}
+	if le.ObjectName() != "1-0:16.7.0*255" || le.Unit != UNIT_WATT || le.Value.DataInt != 256 {
+		t.Fatalf("unexpected entry %s unit %d", le, le.Unit)
+	}
+	if buf.Cursor != len(entry) {
+		t.Fatalf("cursor = %d, want %d", buf.Cursor, len(entry))
+	}
+}
+
+func TestRead_WithShortListEntries(t *testing.T) {
+	short := []byte{0x75, 0x07, 1, 0, 16, 7, 0, 255, 0x62, UNIT_WATT, 0x52, 0x00, 0x53, 0x01, 0x00, 0x01}
+	frame := buildSMLFrame(smlGetListResponse(short))
+	var value float64
+	err := Read(bufio.NewReader(bytes.NewReader(frame)), WithShortListEntries(),
+		WithObisCallback(OctetString{1, 0, 16, 7, 0}, func(le *ListEntry) { value = le.Float() }))
+	if err != nil || value != 256 {
+		t.Fatalf("Read() = %v, value %v", err, value)
+	}
+}
+
+func TestListEntryParse_MissingTrailingFields(t *testing.T) {
+	// objName, status, valTime only
+	entry := []byte{0x73, 0x07, 1, 0, 96, 1, 0, 255, 0x01, 0x01}
+	buf := &Buffer{Bytes: append(entry, 0x00), Cursor: 0}
+	le, err := ListEntryParse(buf)
+	if err != nil {
+		t.Fatalf("ListEntryParse error: %v", err)
+	}
+	if le.ObjectName() != "1-0:96.1.0*255" || le.Value.Typ != 0 || le.ValueSignature != nil {
+		t.Fatalf("unexpected entry %+v", le)
+	}
+}
+
+func TestListEntryParse_InvalidLength(t *testing.T) {
+	buf := &Buffer{Bytes: []byte{0x78, 0x01}, Cursor: 0}
+	if _, err := ListEntryParse(buf); err == nil {
+		t.Fatal("expected error for entry with 8 fields")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithRawFrameCallback
+// ---------------------------------------------------------------------------
+
+func TestRawFrameCallback(t *testing.T) {
+	corrupt := buildSMLFrame([]byte{0x76, 0xFF, 0xFF, 0xFF})
+	data := append(corrupt, fixtureDZG...)
+	var frames [][]byte
+	r := bufio.NewReader(bytes.NewReader(data))
+	err := Read(r, WithRawFrameCallback(func(frame []byte) {
+		frames = append(frames, append([]byte(nil), frame...))
+	}))
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("expected 2 frames including the unparsable one, got %d", len(frames))
+	}
+	if !bytes.Equal(frames[0], []byte{0x76, 0xFF, 0xFF, 0xFF}) {
+		t.Fatalf("unexpected payload of first frame: % x", frames[0])
+	}
+	if !bytes.Equal(frames[1], fixtureDZG[8:len(fixtureDZG)-8]) {
+		t.Fatal("payload of second frame doesn't match fixture")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ListEntry.ValueStringf()
+// ---------------------------------------------------------------------------
+
+func TestValueStringf(t *testing.T) {
+	le := &ListEntry{
+		scaler: -1,
+		Value:  Value{Typ: OCTET_TYPE_INTEGER | TYPE_NUMBER_32, DataInt: 2461},
+	}
+	if got := le.ValueStringf("%.2f"); got != "246.10" {
+		t.Fatalf("ValueStringf() = %q, want %q", got, "246.10")
+	}
+	if got := le.ValueString(); got != "       246.1" {
+		t.Fatalf("ValueString() = %q, want %q", got, "       246.1")
+	}
+	le = &ListEntry{Value: Value{Typ: OCTET_TYPE_BOOLEAN, DataBoolean: true}}
+	if got := le.ValueStringf("%.2f"); got != "true" {
+		t.Fatalf("ValueStringf() on boolean = %q, want %q", got, "true")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: message dispatch
+// ---------------------------------------------------------------------------
+
+func TestReadDispatchesMessageTypes(t *testing.T) {
+	attention := smlMessage(MESSAGE_ATTENTION_RESPONSE, []byte{0x74, 0x03, 0x01, 0x02,
+		0x07, 0x81, 0x81, 0xc7, 0xc7, 0xfd, 0x00, 0x01, 0x01})
+	data := append(append([]byte(nil), fixtureDZG...), buildSMLFrame(attention)...)
+
+	var opens, closes, lists, attentions int
+	var attentionNumber OctetString
+	r := bufio.NewReader(bytes.NewReader(data))
+	err := Read(r,
+		WithOpenResponseCallback(func(msg OpenResponse) { opens++ }),
+		WithCloseResponseCallback(func(msg CloseResponse) { closes++ }),
+		WithGetListResponseCallback(func(msg GetListResponse) {
+			lists++
+			if len(msg.ValList) == 0 {
+				t.Error("GetListResponse without entries")
+			}
+		}),
+		WithAttentionResponseCallback(func(msg AttentionResponse) {
+			attentions++
+			attentionNumber = msg.AttentionNumber
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if opens != 1 || closes != 1 || lists != 1 || attentions != 1 {
+		t.Fatalf("unexpected message counts: open %d close %d list %d attention %d", opens, closes, lists, attentions)
+	}
+	if !bytes.Equal(attentionNumber, OctetString{0x81, 0x81, 0xc7, 0xc7, 0xfd, 0x00}) {
+		t.Fatalf("unexpected attention number % x", attentionNumber)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: TreeParse
+// ---------------------------------------------------------------------------
+
+func TestTreeParse(t *testing.T) {
+	// root without value with one child carrying an u8 value
+	data := []byte{0x73, 0x02, 0xaa, 0x01, 0x71,
+		0x73, 0x02, 0xbb, 0x72, 0x62, PROC_PAR_VALUE_TAG_VALUE, 0x62, 0x2a, 0x01}
+	buf := &Buffer{Bytes: append(data, 0x00), Cursor: 0}
+	tree, err := TreeParse(buf)
+	if err != nil {
+		t.Fatalf("TreeParse error: %v", err)
+	}
+	if !bytes.Equal(tree.ParameterName, OctetString{0xaa}) || tree.ParameterValue != nil {
+		t.Fatalf("unexpected root %+v", tree)
+	}
+	if len(tree.ChildList) != 1 {
+		t.Fatalf("expected 1 child, got %d", len(tree.ChildList))
+	}
+	child := tree.ChildList[0]
+	if child.ParameterValue == nil || child.ParameterValue.Value.DataInt != 0x2a {
+		t.Fatalf("unexpected child %+v", child)
+	}
+	if buf.Cursor != len(data) {
+		t.Fatalf("cursor = %d, want %d", buf.Cursor, len(data))
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Registry
+// ---------------------------------------------------------------------------
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+	r := bufio.NewReader(bytes.NewReader(fixtureDZG))
+	if err := Read(r, WithRegistry(reg)); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	le, ok := reg.Get("1-0:1.8.0*255")
+	if !ok || le.Value.DataInt <= 0 {
+		t.Fatalf("missing 1-0:1.8.0*255 in registry")
+	}
+	snapshot := reg.Snapshot()
+	if _, ok := snapshot["1-0:16.7.0*255"]; !ok {
+		t.Fatal("missing 1-0:16.7.0*255 in snapshot")
+	}
+}
+
+func TestRegistryDelta_Rollover(t *testing.T) {
+	obis := OctetString{1, 0, 1, 8, 0, 255}
+	entry := func(v int64) *ListEntry {
+		return &ListEntry{ObjName: obis, Value: Value{Typ: OCTET_TYPE_UNSIGNED | TYPE_NUMBER_16, DataInt: v}}
+	}
+	reg := NewRegistry()
+	if _, _, ok := reg.Delta(entry(10), 100); ok {
+		t.Fatal("Delta without previous entry should not be ok")
+	}
+	reg.Update(entry(0xfff0))
+
+	delta, rollover, ok := reg.Delta(entry(0xfff8), 100)
+	if !ok || rollover || delta != 8 {
+		t.Fatalf("Delta = %d, %v, %v, want 8, false, true", delta, rollover, ok)
+	}
+	delta, rollover, ok = reg.Delta(entry(0xffe0), 100)
+	if !ok || rollover || delta != -16 {
+		t.Fatalf("Delta = %d, %v, %v, want -16, false, true", delta, rollover, ok)
+	}
+	delta, rollover, ok = reg.Snapshot().Delta(entry(0x0010), 100)
+	if !ok || !rollover || delta != 0x20 {
+		t.Fatalf("Delta = %d, %v, %v, want 32, true, true", delta, rollover, ok)
+	}
+}
+
+func TestRegistryDelta_Rollover48Bit(t *testing.T) {
+	entry := func(v uint64) *ListEntry {
+		le, err := ListEntryParse(&Buffer{Bytes: smlListEntryWidth([]byte{1, 0, 1, 8, 0, 255}, 6, v)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return le
+	}
+	reg := NewRegistry()
+	reg.Update(entry(1<<48 - 10))
+	delta, rollover, ok := reg.Delta(entry(5), 100)
+	if !ok || !rollover || delta != 15 {
+		t.Fatalf("Delta = %d, %v, %v, want 15, true, true", delta, rollover, ok)
+	}
+}
+
+func TestValueWidth(t *testing.T) {
+	for typ, want := range map[uint8]int{
+		OCTET_TYPE_UNSIGNED | TYPE_NUMBER_8:  1,
+		OCTET_TYPE_INTEGER | TYPE_NUMBER_32:  4,
+		OCTET_TYPE_UNSIGNED | TYPE_NUMBER_64: 8,
+		OCTET_TYPE_OCTET_STRING:              0,
+	} {
+		if got := (Value{Typ: typ}).Width(); got != want {
+			t.Errorf("Width(%02x) = %d, want %d", typ, got, want)
+		}
+	}
+}
+
+func TestListEntryIsNumeric(t *testing.T) {
+	for typ, want := range map[uint8]bool{
+		OCTET_TYPE_UNSIGNED | TYPE_NUMBER_8: true,
+		OCTET_TYPE_INTEGER | TYPE_NUMBER_64: true,
+		OCTET_TYPE_BOOLEAN:                  false,
+		OCTET_TYPE_OCTET_STRING:             false,
+		OCTET_TYPE_LIST:                     false,
+	} {
+		if got := (&ListEntry{Value: Value{Typ: typ}}).IsNumeric(); got != want {
+			t.Errorf("IsNumeric(%02x) = %v, want %v", typ, got, want)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: encoding
+// ---------------------------------------------------------------------------
+
+func TestAppendTL(t *testing.T) {
+	for _, tc := range []struct {
+		typ    uint8
+		length int
+		want   []byte
+	}{
+		{OCTET_TYPE_OCTET_STRING, 0, []byte{0x01}},
+		{OCTET_TYPE_OCTET_STRING, 14, []byte{0x0f}},
+		{OCTET_TYPE_OCTET_STRING, 15, []byte{0x81, 0x01}},
+		{OCTET_TYPE_UNSIGNED, 4, []byte{0x65}},
+		{OCTET_TYPE_LIST, 7, []byte{0x77}},
+		{OCTET_TYPE_LIST, 16, []byte{0xf1, 0x00}},
+	} {
+		if got := appendTL(nil, tc.typ, tc.length); !bytes.Equal(got, tc.want) {
+			t.Errorf("appendTL(%02x, %d) = % x, want % x", tc.typ, tc.length, got, tc.want)
+		}
+	}
+}
+
+func TestEncodeGetListRequest(t *testing.T) {
+	req := GetListRequest{
+		ClientID: OctetString{0x01, 0x02, 0x03},
+		ServerID: OctetString{0x0a, 0x01, 0x45, 0x4d, 0x48},
+		Username: OctetString("user"),
+		Password: OctetString("secret"),
+	}
+	file, err := EncodeFile(
+		&Message{TransactionID: OctetString{0x01}, MessageBody: MessageBody{Tag: MESSAGE_OPEN_REQUEST, Data: OpenRequest{ClientID: req.ClientID, ReqFileID: OctetString{0x42}}}},
+		&Message{TransactionID: OctetString{0x02}, MessageBody: MessageBody{Tag: MESSAGE_GET_LIST_REQUEST, Data: req}},
+		&Message{TransactionID: OctetString{0x03}, MessageBody: MessageBody{Tag: MESSAGE_CLOSE_REQUEST, Data: CloseRequest{}}},
+	)
+	if err != nil {
+		t.Fatalf("EncodeFile error: %v", err)
+	}
+	if len(file)%4 != 0 {
+		t.Fatalf("file length %d is not a multiple of 4", len(file))
+	}
+	if crc := crc16Calculate(file, len(file)-2); file[len(file)-2] != byte(crc>>8) || file[len(file)-1] != byte(crc) {
+		t.Fatal("invalid file crc")
+	}
+
+	got, err := readFile(bufio.NewReader(bytes.NewReader(file)))
+	if err != nil {
+		t.Fatalf("readFile error: %v", err)
+	}
+	messages, err := parseFrame(got)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	parsed, ok := messages[1].MessageBody.Data.(GetListRequest)
+	if !ok {
+		t.Fatalf("unexpected message body %T", messages[1].MessageBody.Data)
+	}
+	if !bytes.Equal(parsed.Username, req.Username) || !bytes.Equal(parsed.Password, req.Password) ||
+		!bytes.Equal(parsed.ServerID, req.ServerID) || parsed.ListName != nil {
+		t.Fatalf("unexpected request %+v", parsed)
+	}
+}
+
+func TestFileCRC_Fixture(t *testing.T) {
+	// the file crc of the fixture is calculated like in EncodeFile
+	crc := crc16Calculate(fixtureDZG, len(fixtureDZG)-2)
+	if got := uint16(fixtureDZG[len(fixtureDZG)-2])<<8 | uint16(fixtureDZG[len(fixtureDZG)-1]); got != crc {
+		t.Fatalf("file crc = %04x, want %04x", got, crc)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: buffered readFile fast path
+// ---------------------------------------------------------------------------
+
+// readAllFiles reads all SML files from data using a bufio.Reader of the given size.
+func readAllFiles(data []byte, size int) ([][]byte, []error) {
+	r := bufio.NewReaderSize(bytes.NewReader(data), size)
+	var files [][]byte
+	var errs []error
+	for {
+		fileBytes, err := readFile(r)
+		if err == io.EOF {
+			return files, errs
+		}
+		files = append(files, fileBytes)
+		errs = append(errs, err)
+	}
+}
+
+func TestReadFile_BufferedMatchesChunked(t *testing.T) {
+	inputs := map[string][]byte{
+		"EMH":          fixtureEMH,
+		"ISKRA":        fixtureISKRA,
+		"unrecognized": append([]byte{0x1b, 0x1b, 0x1b, 0x1b, 0x01, 0x01, 0x01, 0x01, 0xaa, 0xbb, 0xcc, 0xdd, 0x1b, 0x1b, 0x1b, 0x1b, 0x02, 0x00, 0x00, 0x00}, fixtureDZG...),
+		"too long":     append(append([]byte{0x1b, 0x1b, 0x1b, 0x1b, 0x01, 0x01, 0x01, 0x01}, make([]byte, maxFileSize)...), fixtureDZG...),
+	}
+	for name, data := range inputs {
+		t.Run(name, func(t *testing.T) {
+			// a buffer of 16 bytes forces the chunked path for every file
+			chunked, chunkedErrs := readAllFiles(data, 16)
+			buffered, bufferedErrs := readAllFiles(data, 8192)
+			if len(chunked) != len(buffered) {
+				t.Fatalf("got %d files, want %d", len(buffered), len(chunked))
+			}
+			for i := range chunked {
+				if !bytes.Equal(chunked[i], buffered[i]) || chunkedErrs[i] != bufferedErrs[i] {
+					t.Fatalf("file %d differs: %v / %v", i, bufferedErrs[i], chunkedErrs[i])
+				}
+			}
+		})
+	}
+}
+
+var benchmarkFixtures = []struct {
+	name string
+	data []byte
+}{
+	{"DZG", fixtureDZG},
+	{"EMH", fixtureEMH},
+	{"HOLLEY", fixtureHOLLEY},
+	{"ISKRA", fixtureISKRA},
+	{"ITRON", fixtureITRON},
+}
+
+// BenchmarkRead measures the full decode path including framing, parsing and callbacks
+func BenchmarkRead(b *testing.B) {
+	for _, fixture := range benchmarkFixtures {
+		b.Run(fixture.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(fixture.data)))
+			for i := 0; i < b.N; i++ {
+				err := Read(bufio.NewReader(bytes.NewReader(fixture.data)),
+					WithObisCallback(OctetString{}, func(*ListEntry) {}))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkParseBytes measures the full decode path of in-memory data
+func BenchmarkParseBytes(b *testing.B) {
+	for _, fixture := range benchmarkFixtures {
+		b.Run(fixture.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(fixture.data)))
+			for i := 0; i < b.N; i++ {
+				if err := ParseBytes(fixture.data, WithObisCallback(OctetString{}, func(*ListEntry) {})); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRead_ParseWorkers measures the throughput of a large multi-file stream parsed on the
+// given number of goroutines
+func BenchmarkRead_ParseWorkers(b *testing.B) {
+	var data []byte
+	for len(data) < 1<<20 {
+		for _, fixture := range benchmarkFixtures {
+			data = append(data, fixture.data...)
+		}
+	}
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				err := Read(bufio.NewReader(bytes.NewReader(data)), WithParseWorkers(workers),
+					WithObisCallback(OctetString{}, func(*ListEntry) {}))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRead_SelectiveParsing measures the decode path when only 1.8.0 is of interest, with and
+// without skipping the other entries while parsing
+func BenchmarkRead_SelectiveParsing(b *testing.B) {
+	for _, fixture := range benchmarkFixtures {
+		for _, selective := range []bool{false, true} {
+			b.Run(fmt.Sprintf("%s/selective=%t", fixture.name, selective), func(b *testing.B) {
+				opts := []ReadOption{WithObisCallback(OctetString{1, 0, 1, 8, 0}, func(*ListEntry) {})}
+				if selective {
+					opts = append(opts, WithSelectiveParsing())
+				}
+				b.ReportAllocs()
+				b.SetBytes(int64(len(fixture.data)))
+				for i := 0; i < b.N; i++ {
+					if err := Read(bufio.NewReader(bytes.NewReader(fixture.data)), opts...); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkReadFile measures framing only
+func BenchmarkReadFile(b *testing.B) {
+	for _, fixture := range benchmarkFixtures {
+		b.Run(fixture.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(fixture.data)))
+			for i := 0; i < b.N; i++ {
+				r := bufio.NewReader(bytes.NewReader(fixture.data))
+				for {
+					if _, err := readFile(r); err == io.EOF {
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkReadFile_Chunked(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		r := bufio.NewReader(bytes.NewReader(fixtureEMH))
+		for {
+			if _, _, err := readStartCounted(r, 0); err == io.EOF {
+				break
+			}
+			readRestCounted(r)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithSanityCheck
+// ---------------------------------------------------------------------------
+
+func TestSanityCheck(t *testing.T) {
+	// octet string value with unit Wh and scaler -1
+	invalid := []byte{0x77, 0x07, 1, 0, 96, 1, 0, 255, 0x01, 0x01, 0x62, UNIT_WATT_HOUR, 0x52, 0xff, 0x03, 0xaa, 0xbb, 0x01}
+	// octet string value without unit and scaler
+	valid := []byte{0x77, 0x07, 1, 0, 96, 1, 1, 255, 0x01, 0x01, 0x01, 0x01, 0x03, 0xaa, 0xbb, 0x01}
+	frame := buildSMLFrame(smlGetListResponse(
+		invalid,
+		valid,
+		smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 1),
+	))
+
+	read := func(opts ...ReadOption) ([]string, []error) {
+		var names []string
+		var errs []error
+		opts = append(opts,
+			WithObisCallback(OctetString{}, func(le *ListEntry) { names = append(names, le.ObjectName()) }),
+			WithErrorCallback(func(err error) { errs = append(errs, err) }),
+		)
+		if err := Read(bufio.NewReader(bytes.NewReader(frame)), opts...); err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		return names, errs
+	}
+
+	if names, errs := read(); len(names) != 3 || len(errs) != 0 {
+		t.Fatalf("without sanity check: got entries %v and errors %v", names, errs)
+	}
+
+	names, errs := read(WithSanityCheck())
+	if len(names) != 2 || names[0] != "1-0:96.1.1*255" {
+		t.Fatalf("with sanity check: unexpected entries %v", names)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrUnitOnNonNumeric) {
+		t.Fatalf("with sanity check: unexpected errors %v", errs)
+	}
+	if ee, ok := errs[0].(*EntryError); !ok || ee.Entry.ObjectName() != "1-0:96.1.0*255" {
+		t.Fatalf("expected *EntryError for 1-0:96.1.0*255, got %v", errs[0])
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ListEntry.DisplayString()
+// ---------------------------------------------------------------------------
+
+func TestDisplayString(t *testing.T) {
+	for _, tc := range []struct {
+		unit   uint8
+		scaler int8
+		value  int64
+		digits int
+		want   string
+	}{
+		{UNIT_WATT_HOUR, -1, 123456789, 6, "012345.6789 kWh"},
+		{UNIT_WATT_HOUR, 2, 1234, 6, "000123.4 kWh"},
+		{UNIT_WATT_HOUR, 3, 1234, 8, "00001234 kWh"},
+		{UNIT_WATT, 0, 523, 4, "0523 W"},
+		{UNIT_VOLT, -1, 2304, 3, "230.4 V"},
+		{0, 0, 42, 3, "042"},
+	} {
+		le := &ListEntry{
+			Unit:   tc.unit,
+			scaler: tc.scaler,
+			Value:  Value{Typ: OCTET_TYPE_UNSIGNED | TYPE_NUMBER_32, DataInt: tc.value},
+		}
+		if got := le.DisplayStringDigits(tc.digits); got != tc.want {
+			t.Errorf("DisplayStringDigits(%d) = %q, want %q", tc.digits, got, tc.want)
+		}
+	}
+
+	le := &ListEntry{Unit: UNIT_WATT_HOUR, scaler: -1, Value: Value{Typ: OCTET_TYPE_UNSIGNED | TYPE_NUMBER_32, DataInt: 123456789}}
+	if got := le.DisplayString(); got != "012345.6789 kWh" {
+		t.Errorf("DisplayString() = %q", got)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: non-SML data between files
+// ---------------------------------------------------------------------------
+
+func TestReadInterleavedLogLines(t *testing.T) {
+	countFiles := func(data []byte) int {
+		var count int
+		r := bufio.NewReader(bytes.NewReader(data))
+		err := Read(r, WithGetListResponseCallback(func(msg GetListResponse) { count++ }))
+		if err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		return count
+	}
+
+	var plain, interleaved []byte
+	for i, fixture := range [][]byte{fixtureDZG, fixtureEMH, fixtureISKRA, fixtureDZG} {
+		plain = append(plain, fixture...)
+		interleaved = append(interleaved, []byte(fmt.Sprintf("2024-03-0%d 12:00:00 capture %d\n", i+1, i))...)
+		// ANSI color codes and stray escape bytes directly before the start sequence
+		interleaved = append(interleaved, "\x1b[32mok\x1b[0m\r\n\x1b\x1b\x1b"...)
+		interleaved = append(interleaved, fixture...)
+		interleaved = append(interleaved, '\n')
+	}
+
+	want := countFiles(plain)
+	if want == 0 {
+		t.Fatal("no files found in fixtures")
+	}
+	if got := countFiles(interleaved); got != want {
+		t.Fatalf("got %d files with interleaved log lines, want %d", got, want)
+	}
+}
+
+func TestReadStart_ExtraEscapeByte(t *testing.T) {
+	data := []byte{0x1b, 0x1b, 0x1b, 0x1b, 0x1b, 0x01, 0x01, 0x01, 0x01}
+	skipped, _, err := readStartCounted(bufio.NewReader(bytes.NewReader(data)), 0)
+	if err != nil {
+		t.Fatalf("readStartCounted error: %v", err)
+	}
+	if skipped != 1 {
+		t.Fatalf("skipped = %d, want 1", skipped)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: GetListResponse.SensorTime()
+// ---------------------------------------------------------------------------
+
+func TestSensorTime(t *testing.T) {
+	parse := func(actSensorTime []byte) GetListResponse {
+		data := append([]byte{0x77, 0x01, 0x01, 0x01}, actSensorTime...)
+		data = append(data, 0x70, 0x01, 0x01, 0x00)
+		list, err := GetListResponseParse(&Buffer{Bytes: data})
+		if err != nil {
+			t.Fatalf("GetListResponseParse error: %v", err)
+		}
+		return list
+	}
+
+	// 2024-01-01T00:00:00Z
+	list := parse([]byte{0x72, 0x62, 0x02, 0x65, 0x65, 0x92, 0x00, 0x80})
+	tm, kind := list.SensorTime()
+	if kind != TIME_KIND_TIMESTAMP || !tm.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("SensorTime() = %v, %d", tm, kind)
+	}
+
+	// local timestamp with 60 minutes local and 60 minutes season time offset
+	list = parse([]byte{0x72, 0x62, 0x03, 0x73, 0x65, 0x65, 0x92, 0x00, 0x80, 0x53, 0x00, 0x3c, 0x53, 0x00, 0x3c})
+	tm, kind = list.SensorTime()
+	if kind != TIME_KIND_LOCAL_TIMESTAMP || tm.Hour() != 2 || !tm.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("SensorTime() = %v, %d", tm, kind)
+	}
+	if list.ActSensorTime != 0x65920080 {
+		t.Fatalf("ActSensorTime = %x", list.ActSensorTime)
+	}
+
+	// sec index
+	list = parse([]byte{0x72, 0x62, 0x01, 0x65, 0x00, 0x00, 0x01, 0x00})
+	if tm, kind = list.SensorTime(); kind != TIME_KIND_SEC_INDEX || !tm.IsZero() {
+		t.Fatalf("SensorTime() = %v, %d", tm, kind)
+	}
+
+	// skipped
+	list = parse([]byte{0x01})
+	if tm, kind = list.SensorTime(); kind != TIME_KIND_NONE || !tm.IsZero() {
+		t.Fatalf("SensorTime() = %v, %d", tm, kind)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithServerIDFilter
+// ---------------------------------------------------------------------------
+
+func TestServerIDFilter(t *testing.T) {
+	// DZG server id is 0a 01 44 5a 47 00 02 82 22 5e, EMH server id 0a 01 45 4d 48 ...
+	data := append(append([]byte(nil), fixtureDZG...), fixtureEMH...)
+
+	for _, tc := range []struct {
+		id   OctetString
+		want int
+	}{
+		{OctetString{0x0a, 0x01, 0x44, 0x5a, 0x47, 0x00, 0x02, 0x82, 0x22, 0x5e}, 1},
+		{OctetString{0x0a, 0x01, 0x44, 0x5a, 0x47}, 1},
+		{OctetString{0x0a, 0x01, 0x44, 0x5a, 0x48}, 0},
+	} {
+		var lists, entries int
+		r := bufio.NewReader(bytes.NewReader(data))
+		err := Read(r,
+			WithServerIDFilter(tc.id),
+			WithGetListResponseCallback(func(msg GetListResponse) { lists++ }),
+			WithObisCallback(OctetString{}, func(le *ListEntry) { entries++ }),
+		)
+		if err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		if lists != tc.want {
+			t.Errorf("filter % x: got %d lists, want %d", []byte(tc.id), lists, tc.want)
+		}
+		if (entries > 0) != (tc.want > 0) {
+			t.Errorf("filter % x: got %d entries", []byte(tc.id), entries)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: DumpCallbacks
+// ---------------------------------------------------------------------------
+
+func TestDumpCallbacks(t *testing.T) {
+	noop := func(le *ListEntry) {}
+	got := DumpCallbacks(
+		WithObisCallback(OctetString{1, 0, 2, 8, 0}, noop),
+		WithObisCallback(OctetString{1, 0, 1, 8, 0}, noop),
+		WithObisCallback(OctetString{1, 0, 1, 8, 0}, noop),
+		WithObisCallback(OctetString{}, noop),
+	)
+	want := "*: 1 callback(s)\n1.0.1.8.0: 2 callback(s)\n1.0.2.8.0: 1 callback(s)\n"
+	if got != want {
+		t.Fatalf("DumpCallbacks() = %q, want %q", got, want)
+	}
+	if got := DumpCallbacks(); got != "" {
+		t.Fatalf("DumpCallbacks() without callbacks = %q", got)
+	}
+
+	all := func([]*ListEntry) {}
+	got = DumpCallbacks(
+		WithObisCallback(OctetString{1, 0, 1, 8, 0}, noop),
+		WithObisCallbackAll(OctetString{1, 0, 2, 8}, all),
+		WithObisCallbackAll(OctetString{1, 0, 2, 8}, all),
+		WithObisCallbackAll(OctetString{}, all),
+	)
+	want = "1.0.1.8.0: 1 callback(s)\n*: 1 callback(s) for all entries\n1.0.2.8: 2 callback(s) for all entries\n"
+	if got != want {
+		t.Fatalf("DumpCallbacks() with WithObisCallbackAll = %q, want %q", got, want)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: reserved type classes
+// ---------------------------------------------------------------------------
+
+func TestReservedType(t *testing.T) {
+	for _, tl := range []byte{0x12, 0x22, 0x32} {
+		buf := &Buffer{Bytes: []byte{tl, 0x00}, Cursor: 0}
+		_, err := buf.ValueParse()
+		if !errors.Is(err, ErrReservedType) {
+			t.Errorf("ValueParse(%02x): expected ErrReservedType, got %v", tl, err)
+		}
+
+		buf = &Buffer{Bytes: []byte{tl, 0x00}, Cursor: 0}
+		_, err = buf.U8Parse()
+		if !errors.Is(err, ErrReservedType) {
+			t.Errorf("U8Parse(%02x): expected ErrReservedType, got %v", tl, err)
+		}
+
+		buf = &Buffer{Bytes: []byte{tl, 0x00}, Cursor: 0}
+		_, err = buf.OctetStringParse()
+		if !errors.Is(err, ErrReservedType) {
+			t.Errorf("OctetStringParse(%02x): expected ErrReservedType, got %v", tl, err)
+		}
+	}
+
+	// known but unexpected types are no reserved types
+	buf := &Buffer{Bytes: []byte{0x42, 0x01}, Cursor: 0}
+	if _, err := buf.U8Parse(); err == nil || errors.Is(err, ErrReservedType) {
+		t.Fatalf("U8Parse(boolean): unexpected error %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: unit/scaler presence
+// ---------------------------------------------------------------------------
+
+func TestListEntryParse_UnitScalerPresence(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		unitScaler         []byte
+		hasUnit, hasScaler bool
+		wantUnit           uint8
+		wantFloat          float64
+	}{
+		{"both present", []byte{0x62, 0x00, 0x52, 0x00}, true, true, 0, 7},
+		{"both skipped", []byte{0x01, 0x01}, false, false, 0, 7},
+		{"unit skipped", []byte{0x01, 0x52, 0xff}, false, true, 0, 0.7},
+		{"scaler skipped", []byte{0x62, UNIT_WATT, 0x01}, true, false, UNIT_WATT, 7},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			entry := []byte{0x77, 0x07, 1, 0, 16, 7, 0, 255, 0x01, 0x01}
+			entry = append(entry, tc.unitScaler...)
+			entry = append(entry, 0x62, 0x07, 0x01)
+			le, err := ListEntryParse(&Buffer{Bytes: append(entry, 0x00)})
+			if err != nil {
+				t.Fatalf("ListEntryParse error: %v", err)
+			}
+			if le.HasUnit() != tc.hasUnit || le.HasScaler() != tc.hasScaler {
+				t.Fatalf("HasUnit() = %v, HasScaler() = %v", le.HasUnit(), le.HasScaler())
+			}
+			if le.Unit != tc.wantUnit || math.Abs(le.Float()-tc.wantFloat) > 1e-9 {
+				t.Fatalf("unit = %d, Float() = %f", le.Unit, le.Float())
+			}
+		})
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: NewReplayReader
+// ---------------------------------------------------------------------------
+
+func TestSplitFiles(t *testing.T) {
+	data := append(append([]byte("garbage"), fixtureDZG...), fixtureDZG...)
+	data = append(data, 0x01, 0x02)
+	chunks := splitFiles(data)
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %d", len(chunks))
+	}
+	if !bytes.Equal(chunks[0], data[:7+len(fixtureDZG)]) || !bytes.Equal(chunks[1], fixtureDZG) {
+		t.Fatal("unexpected chunk boundaries")
+	}
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("chunks don't add up to data")
+	}
+}
+
+func TestReplayReader(t *testing.T) {
+	data := append(append(append([]byte(nil), fixtureDZG...), fixtureDZG...), fixtureDZG...)
+	const interval = 30 * time.Millisecond
+
+	clock := time.Unix(1600000000, 0)
+	var sleeps []time.Duration
+	rr := NewReplayReader(data, interval).(*replayReader)
+	rr.now = func() time.Time { return clock }
+	rr.sleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+		clock = clock.Add(d)
+	}
+
+	var times []time.Time
+	start := clock
+	err := Read(bufio.NewReader(rr), WithGetListResponseCallback(func(msg GetListResponse) {
+		times = append(times, clock)
+		clock = clock.Add(10 * time.Millisecond) // time spent handling the file
+	}))
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if len(times) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(times))
+	}
+	if !times[0].Equal(start) {
+		t.Fatalf("first file delayed by %v", times[0].Sub(start))
+	}
+	for i := 1; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); d != interval {
+			t.Fatalf("file %d delivered after %v, want %v", i, d, interval)
+		}
+	}
+	if len(sleeps) != 2 || sleeps[0] != interval-10*time.Millisecond {
+		t.Fatalf("sleeps = %v, want 2 of %v", sleeps, interval-10*time.Millisecond)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ReadOpen
+// ---------------------------------------------------------------------------
+
+func TestReadOpen(t *testing.T) {
+	r := bufio.NewReader(bytes.NewReader(fixtureDZG))
+	open, err := ReadOpen(r)
+	if err != nil {
+		t.Fatalf("ReadOpen error: %v", err)
+	}
+	want := OctetString{0x0a, 0x01, 0x44, 0x5a, 0x47, 0x00, 0x02, 0x82, 0x22, 0x5e}
+	if !bytes.Equal(open.ServerID, want) {
+		t.Fatalf("ServerID = % x, want % x", open.ServerID, want)
+	}
+	if len(open.ReqFileID) == 0 {
+		t.Fatal("missing ReqFileID")
+	}
+}
+
+func TestReadOpen_NoOpenResponse(t *testing.T) {
+	frame := buildSMLFrame(smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, 1)))
+	if _, err := ReadOpen(bufio.NewReader(bytes.NewReader(frame))); err == nil {
+		t.Fatal("expected error for file without OpenResponse")
+	}
+	if _, err := ReadOpen(bufio.NewReader(bytes.NewReader(nil))); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ParseObis / ObjectNameHex
+// ---------------------------------------------------------------------------
+
+func TestParseObis(t *testing.T) {
+	for s, want := range map[string]OctetString{
+		"1-0:1.8.0*255":              {1, 0, 1, 8, 0, 255},
+		"1-0:16.7.0&255":             {1, 0, 16, 7, 0, 255},
+		"1-0:2.8.1":                  {1, 0, 2, 8, 1, 255},
+		"129-129:199.130.3*255":      {0x81, 0x81, 0xc7, 0x82, 0x03, 0xff},
+		"0x81-0x81:0xc7.0x82.3*0xff": {0x81, 0x81, 0xc7, 0x82, 0x03, 0xff},
+		"8181c78203ff":               {0x81, 0x81, 0xc7, 0x82, 0x03, 0xff},
+		"7-0:3.0.0*255":              {7, 0, 3, 0, 0, 255},
+		"1-0:1.8.010*255":            {1, 0, 1, 8, 10, 255},
+		"1-0:1.8.0*0255":             {1, 0, 1, 8, 0, 255},
+		"01-00:01.08.00":             {1, 0, 1, 8, 0, 255},
+		"1-0:0x10.7.0*255":           {1, 0, 16, 7, 0, 255},
+	} {
+		got, err := ParseObis(s)
+		if err != nil {
+			t.Errorf("ParseObis(%q) error: %v", s, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("ParseObis(%q) = % x, want % x", s, got, want)
+		}
+	}
+
+	for _, s := range []string{"", "1.8.0", "1-0:1.8.0*256", "1-0:1.8.x*255", "1:0-1.8.0*255", "8181c78203fg", "1-0:1.8.0*0x100"} {
+		if _, err := ParseObis(s); err == nil {
+			t.Errorf("ParseObis(%q): expected error", s)
+		}
+	}
+}
+
+func TestObjectName_AbstractObject(t *testing.T) {
+	le := &ListEntry{ObjName: OctetString{0x81, 0x81, 0xc7, 0x82, 0x03, 0xff}}
+	if got := le.ObjectName(); got != "129-129:199.130.3*255" {
+		t.Fatalf("ObjectName() = %q", got)
+	}
+	if got := le.ObjectNameHex(); got != "81-81:C7.82.03*FF" {
+		t.Fatalf("ObjectNameHex() = %q", got)
+	}
+	code, err := ParseObis(le.ObjectName())
+	if err != nil || !bytes.Equal(code, le.ObjName) {
+		t.Fatalf("ParseObis(ObjectName()) = % x, %v", code, err)
+	}
+}
+
+func TestObjectName_Short(t *testing.T) {
+	le := &ListEntry{ObjName: OctetString{0x01, 0x02}}
+	if got := le.ObjectName(); got != "0102" {
+		t.Fatalf("ObjectName() = %q", got)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithDedupe
+// ---------------------------------------------------------------------------
+
+func TestRead_WithDedupe(t *testing.T) {
+	obis := []byte{1, 0, 1, 8, 0, 255}
+	frame := func(value uint32) []byte {
+		return buildSMLFrame(smlGetListResponse(smlListEntry(obis, UNIT_WATT_HOUR, -1, value)))
+	}
+	var data []byte
+	for _, v := range []uint32{100, 100, 101, 101, 100} {
+		data = append(data, frame(v)...)
+	}
+
+	read := func(opts ...ReadOption) []int64 {
+		var values []int64
+		opts = append(opts, WithObisCallback(OctetString(obis), func(le *ListEntry) {
+			values = append(values, le.Value.DataInt)
+		}))
+		if err := Read(bufio.NewReader(bytes.NewReader(data)), opts...); err != nil {
+			t.Fatal(err)
+		}
+		return values
+	}
+
+	if got := read(); len(got) != 5 {
+		t.Fatalf("without dedupe: got %v", got)
+	}
+	if got := fmt.Sprint(read(WithDedupe())); got != "[100 101 100]" {
+		t.Fatalf("with dedupe: got %s", got)
+	}
+	// state doesn't carry over between calls
+	if got := fmt.Sprint(read(WithDedupe())); got != "[100 101 100]" {
+		t.Fatalf("second call with dedupe: got %s", got)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: GetListResponse with skipped optionals
+// ---------------------------------------------------------------------------
+
+func TestGetListResponseParse_SkippedListNameAndSensorTime(t *testing.T) {
+	data := []byte{0x77, 0x01, 0x03, 0x01, 0x02, 0x01, 0x01, 0x72}
+	data = append(data, smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 1234)...)
+	data = append(data, smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 56)...)
+	// skipped listSignature, actGatewayTime present to check the cursor stays aligned
+	data = append(data, 0x01, 0x72, 0x62, 0x01, 0x65, 0x00, 0x00, 0x10, 0x00)
+
+	buf := &Buffer{Bytes: data}
+	list, err := GetListResponseParse(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.ListName != nil {
+		t.Errorf("ListName = % x, want nil", list.ListName)
+	}
+	if list.ActSensorTime != 0 {
+		t.Errorf("ActSensorTime = %d, want 0", list.ActSensorTime)
+	}
+	if _, kind := list.SensorTime(); kind != TIME_KIND_NONE {
+		t.Errorf("SensorTime kind = %d, want TIME_KIND_NONE", kind)
+	}
+	if !bytes.Equal(list.ServerID, OctetString{0x01, 0x02}) {
+		t.Errorf("ServerID = % x", list.ServerID)
+	}
+	if len(list.ValList) != 2 || list.ValList[0].Value.DataInt != 1234 || list.ValList[1].Value.DataInt != 56 {
+		t.Fatalf("unexpected entries %v", list.ValList)
+	}
+	if list.ActGatewayTime != 0x1000 {
+		t.Errorf("ActGatewayTime = %d, want %d", list.ActGatewayTime, 0x1000)
+	}
+	if buf.Cursor != len(data) {
+		t.Errorf("cursor at %d, want %d", buf.Cursor, len(data))
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: FormatServerID
+// ---------------------------------------------------------------------------
+
+func TestFormatServerID(t *testing.T) {
+	for _, tc := range []struct {
+		id   OctetString
+		want string
+	}{
+		{OctetString{0x0a, 0x01, 'D', 'Z', 'G', 0x00, 0x02, 0x82, 0x22, 0x5e}, "1 DZG 00 42082910"},
+		{OctetString{0x09, 0x01, 'I', 'S', 'K', 0x00, 0x04, 0x03, 0xdf, 0x63}, "1 ISK 00 67362659"},
+		{OctetString{0x0a, 0x01, 'E', 'M', 'H', 0x00, 0x00, 0xbc, 0x61, 0x4e}, "1 EMH 00 12345678"},
+		{OctetString{0x06, 'E', 'M', 'H', 0x01, 0x02, 0x71, 0x53, 0xc8, 0xc6}, "06454d4801027153c8c6"},
+		{OctetString{0x01, 0x02}, "0102"},
+		{nil, ""},
+	} {
+		if got := FormatServerID(tc.id); got != tc.want {
+			t.Errorf("FormatServerID(% x) = %q, want %q", tc.id, got, tc.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: odd number widths
+// ---------------------------------------------------------------------------
+
+func TestValueParse_OddWidths(t *testing.T) {
+	for _, tc := range []struct {
+		data  []byte
+		want  int64
+		width int
+	}{
+		{[]byte{0x64, 0x01, 0x02, 0x03}, 0x010203, 4},
+		{[]byte{0x54, 0x01, 0x02, 0x03}, 0x010203, 4},
+		{[]byte{0x54, 0xff, 0xff, 0xfe}, -2, 4},
+		{[]byte{0x66, 0x01, 0x02, 0x03, 0x04, 0x05}, 0x0102030405, 8},
+		{[]byte{0x56, 0x80, 0x00, 0x00, 0x00, 0x00}, -0x8000000000, 8},
+		{[]byte{0x67, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 0xffffffffffff, 8},
+		{[]byte{0x57, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}, 0x0100000000, 8},
+		{[]byte{0x57, 0xff, 0xff, 0xff, 0xff, 0xfc, 0x18}, -1000, 8},
+		{[]byte{0x52, 0xff}, -1, 1},
+		{[]byte{0x62, 0xff}, 255, 1},
+	} {
+		buf := &Buffer{Bytes: tc.data}
+		v, err := buf.ValueParse()
+		if err != nil {
+			t.Errorf("ValueParse(% x) error: %v", tc.data, err)
+			continue
+		}
+		if v.DataInt != tc.want {
+			t.Errorf("ValueParse(% x) = %d, want %d", tc.data, v.DataInt, tc.want)
+		}
+		if v.Width() != tc.width {
+			t.Errorf("ValueParse(% x) width = %d, want %d", tc.data, v.Width(), tc.width)
+		}
+		if buf.Cursor != len(tc.data) {
+			t.Errorf("ValueParse(% x) cursor at %d", tc.data, buf.Cursor)
+		}
+	}
+}
+
+func TestNumberParse_Truncated(t *testing.T) {
+	buf := &Buffer{Bytes: []byte{0x65, 0x01, 0x02}}
+	if _, err := buf.U32Parse(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: parse error context
+// ---------------------------------------------------------------------------
+
+func TestParseFrame_ErrorContext(t *testing.T) {
+	obis := []byte{1, 0, 1, 8, 0, 255}
+	bad := smlListEntry(obis, UNIT_WATT_HOUR, 0, 1)
+	bad[12] = 0x62 // scaler encoded as unsigned instead of integer
+	payload := append(smlGetListResponse(), smlGetListResponse(
+		smlListEntry(obis, UNIT_WATT_HOUR, 0, 1),
+		smlListEntry(obis, UNIT_WATT_HOUR, 0, 2),
+		smlListEntry(obis, UNIT_WATT_HOUR, 0, 3),
+		bad,
+	)...)
+
+	_, err := parseFrame(buildSMLFrame(payload))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	want := "message 1: valList: entry 3: scaler: unexpected type"
+	if !strings.HasPrefix(err.Error(), want) {
+		t.Fatalf("error = %q, want prefix %q", err, want)
+	}
+}
+
+func TestParseFrame_ErrorContextOpenResponse(t *testing.T) {
+	// smlVersion encoded as integer instead of unsigned
+	body := []byte{0x76, 0x01, 0x01, 0x01, 0x01, 0x01, 0x52, 0x01}
+	_, err := parseFrame(buildSMLFrame(smlMessage(MESSAGE_OPEN_RESPONSE, body)))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	want := "message 0: smlVersion: unexpected type"
+	if !strings.HasPrefix(err.Error(), want) {
+		t.Fatalf("error = %q, want prefix %q", err, want)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: GetProfileListResponse / ProfileSeries
+// ---------------------------------------------------------------------------
+
+func TestGetProfileListResponseParse(t *testing.T) {
+	msgs, err := parseFrame(buildSMLFrame(smlProfileListResponse(1600000000, 1234, 7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, ok := msgs[0].MessageBody.Data.(GetProfileListResponse)
+	if !ok {
+		t.Fatalf("unexpected message body %T", msgs[0].MessageBody.Data)
+	}
+	if resp.RegPeriod != 900 || resp.ValTime != 1600000000 || resp.ActTime != 0x1000 {
+		t.Errorf("unexpected header %+v", resp)
+	}
+	if len(resp.ParameterTreePath) != 1 || !bytes.Equal(resp.ParameterTreePath[0], OctetString{0xaa, 0xbb}) {
+		t.Errorf("ParameterTreePath = %v", resp.ParameterTreePath)
+	}
+	if len(resp.PeriodList) != 2 {
+		t.Fatalf("got %d period entries, want 2", len(resp.PeriodList))
+	}
+}
+
+func TestProfileSeries(t *testing.T) {
+	var series []TimeSeriesPoint
+	for i, v := range []uint32{1234, 1240, 1251} {
+		msgs, err := parseFrame(buildSMLFrame(smlProfileListResponse(1600000000+uint32(i)*900, v, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := msgs[0].MessageBody.Data.(GetProfileListResponse)
+		series = append(series, ProfileSeries(&resp, OctetString{1, 0, 1, 8, 0, 255})...)
+	}
+
+	want := []TimeSeriesPoint{{1600000000, 123.4}, {1600000900, 124.0}, {1600001800, 125.1}}
+	if len(series) != len(want) {
+		t.Fatalf("got %v, want %v", series, want)
+	}
+	for i := range want {
+		if series[i].Time != want[i].Time || math.Abs(series[i].Value-want[i].Value) > 1e-9 {
+			t.Errorf("point %d = %v, want %v", i, series[i], want[i])
+		}
+	}
+
+	resp := GetProfileListResponse{}
+	if got := ProfileSeries(&resp, OctetString{1, 0, 1, 8, 0, 255}); len(got) != 0 {
+		t.Errorf("empty response: got %v", got)
+	}
+
+	resp.PeriodList = []*PeriodEntry{{
+		ObjName: OctetString{1, 0, 1, 8, 0, 255},
+		Value:   Value{Typ: OCTET_TYPE_OCTET_STRING, DataBytes: OctetString("n/a")},
+	}}
+	if got := ProfileSeries(&resp, OctetString{1, 0, 1, 8, 0, 255}); len(got) != 0 {
+		t.Errorf("non-numeric value: got %v", got)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: OBIS patterns
+// ---------------------------------------------------------------------------
+
+func TestParseObisPattern(t *testing.T) {
+	W := OBIS_WILDCARD
+	for s, want := range map[string]ObisPattern{
+		"1-0:1.8.*":     {1, 0, 1, 8, W},
+		"1-0:*.7.0*255": {1, 0, W, 7, 0, 255},
+		"1-0:1.8.0*255": {1, 0, 1, 8, 0, 255},
+		"1-0:1.8.0**":   {1, 0, 1, 8, 0, W},
+		"*-*:96":        {W, W, 96},
+		"1-0":           {1, 0},
+		"01-00:01.08.*": {1, 0, 1, 8, W},
+		"1-0:1.8.010":   {1, 0, 1, 8, 10},
+		"1-0:0x60":      {1, 0, 96},
+	} {
+		got, err := ParseObisPattern(s)
+		if err != nil {
+			t.Errorf("ParseObisPattern(%q) error: %v", s, err)
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("ParseObisPattern(%q) = %v, want %v", s, got, want)
+		}
+	}
+
+	for _, s := range []string{"", "1--0", "1-0:1.8.0*255.1", "1-0:256.8.0", "1-0:x.8.0"} {
+		if _, err := ParseObisPattern(s); err == nil {
+			t.Errorf("ParseObisPattern(%q): expected error", s)
+		}
+	}
+}
+
+func TestReadObisPattern_Tariffs(t *testing.T) {
+	pattern, err := ParseObisPattern("1-0:1.8.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]int{}
+	err = Read(bufio.NewReader(bytes.NewReader(fixtureEMH)), WithObisPatternCallback(pattern, func(le *ListEntry) {
+		seen[le.ObjectName()]++
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obis := range []string{"1-0:1.8.0*255", "1-0:1.8.1*255", "1-0:1.8.2*255"} {
+		if seen[obis] == 0 {
+			t.Errorf("%s not matched", obis)
+		}
+	}
+	if len(seen) != 3 {
+		t.Errorf("unexpected matches %v", seen)
+	}
+}
+
+func TestReadObisPattern_WildcardAndExact(t *testing.T) {
+	var exact, wildcard int
+	pattern := ObisPattern{1, 0, OBIS_WILDCARD, 7, 0}
+	err := Read(bufio.NewReader(bytes.NewReader(fixtureISKRA)),
+		WithObisCallback(OctetString{1, 0, 16, 7, 0}, func(*ListEntry) { exact++ }),
+		WithObisPatternCallback(pattern, func(*ListEntry) { wildcard++ }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exact == 0 || wildcard != 4*exact {
+		t.Fatalf("exact = %d, wildcard = %d (expected 4 phases per exact match)", exact, wildcard)
+	}
+	if got := DumpCallbacks(WithObisPatternCallback(pattern, func(*ListEntry) {})); got != "1.0.*.7.0: 1 callback(s)\n" {
+		t.Fatalf("DumpCallbacks() = %q", got)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: truncated file at EOF
+// ---------------------------------------------------------------------------
+
+func TestReadFile_TruncatedAtEOF(t *testing.T) {
+	frame := buildSMLFrame(smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, 1)))
+	// cut within a chunk, at a chunk boundary and within the end sequence
+	for _, cut := range []int{8, 11, 16, len(frame) - 6, len(frame) - 1} {
+		r := bufio.NewReaderSize(bytes.NewReader(frame[:cut]), 16)
+		if _, err := readFile(r); err != io.EOF {
+			t.Errorf("cut at %d: got %v, want io.EOF", cut, err)
+		}
+	}
+}
+
+func TestRead_TruncatedFrameAfterValidOne(t *testing.T) {
+	valid := buildSMLFrame(smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, 42)))
+	data := append(append([]byte(nil), valid...), valid[:len(valid)/2+1]...)
+
+	for _, size := range []int{16, 4096} {
+		var values []int64
+		var errs []error
+		err := Read(bufio.NewReaderSize(bytes.NewReader(data), size),
+			WithObisCallback(OctetString{1, 0, 1, 8, 0}, func(le *ListEntry) {
+				values = append(values, le.Value.DataInt)
+			}),
+			WithErrorCallback(func(err error) { errs = append(errs, err) }))
+		if err != nil {
+			t.Fatalf("buffer size %d: Read returned %v", size, err)
+		}
+		if len(values) != 1 || values[0] != 42 {
+			t.Errorf("buffer size %d: got values %v", size, values)
+		}
+		if len(errs) != 0 {
+			t.Errorf("buffer size %d: unexpected errors %v", size, errs)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithTransform
+// ---------------------------------------------------------------------------
+
+func TestRead_WithTransform(t *testing.T) {
+	frame := buildSMLFrame(smlGetListResponse(
+		smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 1000),
+		smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 50),
+	))
+
+	got := map[string]*ListEntry{}
+	err := Read(bufio.NewReader(bytes.NewReader(frame)),
+		WithTransform(OctetString{1, 0, 16, 7}, func(v float64) float64 { return v * 40 }),
+		WithTransform(OctetString{1, 0, 16}, func(v float64) float64 { return v + 1 }),
+		WithObisCallback(OctetString{}, func(le *ListEntry) { got[le.ObjectName()] = le }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	power := got["1-0:16.7.0*255"]
+	if power == nil || power.Float() != 2001 || power.Value.DataInt != 50 {
+		t.Fatalf("power entry %v", power)
+	}
+	if s := power.ValueStringf("%.0f"); s != "2001" {
+		t.Errorf("ValueStringf() = %q", s)
+	}
+	if energy := got["1-0:1.8.0*255"]; energy == nil || energy.Float() != 100 {
+		t.Fatalf("energy entry %v should not be transformed", energy)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ReadDeviceInfo
+// ---------------------------------------------------------------------------
+
+func TestReadDeviceInfo(t *testing.T) {
+	info, err := ReadDeviceInfo(bufio.NewReader(bytes.NewReader(fixtureDZG)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ServerID != "1 DZG 00 42082910" {
+		t.Errorf("ServerID = %q", info.ServerID)
+	}
+	if info.SerialID != "1 DZG 00 42082910" {
+		t.Errorf("SerialID = %q", info.SerialID)
+	}
+	if info.Manufacturer != "DZG" {
+		t.Errorf("Manufacturer = %q", info.Manufacturer)
+	}
+	if info.FirmwareVersion != "" {
+		t.Errorf("FirmwareVersion = %q, want empty", info.FirmwareVersion)
+	}
+	if info.Identification["1-0:96.50.1*1"] != "DZG" {
+		t.Errorf("Identification = %v", info.Identification)
+	}
+
+	info, err = ReadDeviceInfo(bufio.NewReader(bytes.NewReader(fixtureEMH)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Manufacturer != "EMH" || info.SerialID != "06454d4801027153c8c6" {
+		t.Errorf("unexpected device info %+v", info)
+	}
+}
+
+func TestReadDeviceInfo_EOF(t *testing.T) {
+	if _, err := ReadDeviceInfo(bufio.NewReader(bytes.NewReader(nil))); err != io.EOF {
+		t.Fatalf("got %v, want io.EOF", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ReadUntil
+// ---------------------------------------------------------------------------
+
+func TestReadUntil(t *testing.T) {
+	frame := buildSMLFrame(smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, 1)))
+	data := bytes.Repeat(frame, 3)
+	start := time.Unix(1600000000, 0)
+
+	count := func(deadline time.Time, step time.Duration) int {
+		clock := start
+		n := 0
+		err := ReadUntil(bufio.NewReader(bytes.NewReader(data)), deadline,
+			WithObisCallback(OctetString{}, func(*ListEntry) {
+				n++
+				clock = clock.Add(step)
+			}),
+			func(o *options) { o.now = func() time.Time { return clock } })
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if n := count(start.Add(time.Hour), 0); n != 3 {
+		t.Errorf("future deadline: got %d entries, want 3", n)
+	}
+	if n := count(start.Add(-time.Second), 0); n != 0 {
+		t.Errorf("passed deadline: got %d entries, want 0", n)
+	}
+	if n := count(start, 0); n != 0 {
+		t.Errorf("deadline now: got %d entries, want 0", n)
+	}
+	// the deadline passes while the first file's callbacks run
+	if n := count(start.Add(10*time.Millisecond), 20*time.Millisecond); n != 1 {
+		t.Errorf("deadline passing during first file: got %d entries, want 1", n)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ObisFields
+// ---------------------------------------------------------------------------
+
+func TestObisFields(t *testing.T) {
+	le := &ListEntry{ObjName: OctetString{1, 0, 1, 8, 2, 255}}
+	a, b, c, d, e, f, ok := le.ObisFields()
+	if !ok || a != 1 || b != 0 || c != 1 || d != 8 || e != 2 || f != 255 {
+		t.Fatalf("ObisFields() = %d %d %d %d %d %d %v", a, b, c, d, e, f, ok)
+	}
+	if _, _, _, _, _, _, ok := (OctetString{1, 0, 1}).ObisFields(); ok {
+		t.Fatal("ObisFields() of short code: ok should be false")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: reading from pipes
+// ---------------------------------------------------------------------------
+
+func TestRead_Pipe(t *testing.T) {
+	data := append(append([]byte(nil), fixtureEMH...), fixtureEMH[:37]...)
+
+	collect := func(r io.Reader) ([]string, []error, error) {
+		var entries []string
+		var errs []error
+		err := Read(bufio.NewReader(r),
+			WithObisCallback(OctetString{}, func(le *ListEntry) { entries = append(entries, le.String()) }),
+			WithErrorCallback(func(err error) { errs = append(errs, err) }))
+		return entries, errs, err
+	}
+
+	wantEntries, wantErrs, err := collect(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < len(data); i += 7 {
+			end := i + 7
+			if end > len(data) {
+				end = len(data)
+			}
+			if _, err := pw.Write(data[i:end]); err != nil {
+				return
+			}
+		}
+		pw.Close()
+	}()
+
+	entries, errs, err := collect(pr)
+	if err != nil {
+		t.Fatalf("Read from pipe returned %v", err)
+	}
+	if len(entries) == 0 || fmt.Sprint(entries) != fmt.Sprint(wantEntries) {
+		t.Errorf("pipe delivered %d entries, file %d", len(entries), len(wantEntries))
+	}
+	if fmt.Sprint(errs) != fmt.Sprint(wantErrs) {
+		t.Errorf("pipe errors %v, file errors %v", errs, wantErrs)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Rat
+// ---------------------------------------------------------------------------
+
+func TestListEntryRat(t *testing.T) {
+	for _, tc := range []struct {
+		value  int64
+		scaler int8
+		want   string
+	}{
+		{12345, -1, "2469/2"},
+		{12345, -4, "2469/2000"},
+		{-7, 2, "-700/1"},
+		{1, 0, "1/1"},
+	} {
+		le := &ListEntry{scaler: tc.scaler, Value: Value{Typ: OCTET_TYPE_INTEGER | TYPE_NUMBER_64, DataInt: tc.value}}
+		if got := le.Rat().String(); got != tc.want {
+			t.Errorf("Rat() of %d*10^%d = %s, want %s", tc.value, tc.scaler, got, tc.want)
+		}
+	}
+
+	// summing 0.1 ten thousand times is exact
+	sum := new(big.Rat)
+	le := &ListEntry{scaler: -1, Value: Value{Typ: OCTET_TYPE_UNSIGNED | TYPE_NUMBER_32, DataInt: 1}}
+	for i := 0; i < 10000; i++ {
+		sum.Add(sum, le.Rat())
+	}
+	if got := sum.FloatString(1); got != "1000.0" {
+		t.Errorf("sum = %s", got)
+	}
+
+	if r := (&ListEntry{Value: Value{Typ: OCTET_TYPE_OCTET_STRING}}).Rat(); r != nil {
+		t.Errorf("Rat() of octet string = %v, want nil", r)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ListSignature presence
+// ---------------------------------------------------------------------------
+
+func TestGetListResponse_HasListSignature(t *testing.T) {
+	entry := smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, 1)
+	parse := func(signature ...byte) GetListResponse {
+		data := append([]byte{0x77, 0x01, 0x03, 0x01, 0x02, 0x01, 0x01, 0x71}, entry...)
+		data = append(append(data, signature...), 0x01)
+		list, err := GetListResponseParse(&Buffer{Bytes: data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return list
+	}
+
+	if list := parse(0x01); list.HasListSignature() || list.ListSignature != nil {
+		t.Errorf("skipped signature: HasListSignature() = %v, ListSignature = % x", list.HasListSignature(), list.ListSignature)
+	}
+	if list := parse(0x03, 0xaa, 0xbb); !list.HasListSignature() || !bytes.Equal(list.ListSignature, OctetString{0xaa, 0xbb}) {
+		t.Errorf("present signature: HasListSignature() = %v, ListSignature = % x", list.HasListSignature(), list.ListSignature)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ParseBytes
+// ---------------------------------------------------------------------------
+
+func TestParseBytes(t *testing.T) {
+	var n int
+	if err := ParseBytes(fixtureDZG, WithObisCallback(OctetString{1, 0, 1, 8, 0}, func(*ListEntry) { n++ })); err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("no entries parsed")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithSampleInterval
+// ---------------------------------------------------------------------------
+
+func TestRead_WithSampleInterval(t *testing.T) {
+	var data []byte
+	for v := uint32(1); v <= 3; v++ {
+		data = append(data, buildSMLFrame(smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, v)))...)
+	}
+
+	read := func(d time.Duration) []int64 {
+		var values []int64
+		var lists int
+		err := Read(bufio.NewReader(bytes.NewReader(data)), WithSampleInterval(d),
+			WithObisCallback(OctetString{}, func(le *ListEntry) { values = append(values, le.Value.DataInt) }),
+			WithGetListResponseCallback(func(GetListResponse) { lists++ }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lists != len(values) {
+			t.Errorf("interval %v: %d lists for %d values", d, lists, len(values))
+		}
+		return values
+	}
+
+	if got := fmt.Sprint(read(0)); got != "[1 2 3]" {
+		t.Errorf("without interval: got %s", got)
+	}
+	if got := fmt.Sprint(read(time.Hour)); got != "[1]" {
+		t.Errorf("with interval: got %s", got)
+	}
+
+	// a file every 40 minutes
+	clock := time.Unix(1600000000, 0)
+	var values []int64
+	err := Read(bufio.NewReader(bytes.NewReader(data)), WithSampleInterval(time.Hour),
+		WithRawFrameCallback(func([]byte) { clock = clock.Add(40 * time.Minute) }),
+		WithObisCallback(OctetString{}, func(le *ListEntry) { values = append(values, le.Value.DataInt) }),
+		func(o *options) { o.now = func() time.Time { return clock } })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(values); got != "[1 3]" {
+		t.Errorf("with clock: got %s", got)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: zero-length numbers
+// ---------------------------------------------------------------------------
+
+func TestValueParse_ZeroLength(t *testing.T) {
+	for _, tl := range []byte{0x61, 0x51} {
+		buf := &Buffer{Bytes: []byte{tl, 0x62, 0x05}}
+		v, err := buf.ValueParse()
+		if err != nil {
+			t.Fatalf("ValueParse(%02x) error: %v", tl, err)
+		}
+		if v.DataInt != 0 || v.Typ&OCTET_TYPE_FIELD != tl&OCTET_TYPE_FIELD {
+			t.Errorf("ValueParse(%02x) = %+v, want 0", tl, v)
+		}
+		if buf.Cursor != 1 {
+			t.Errorf("ValueParse(%02x) advanced to %d, want 1", tl, buf.Cursor)
+		}
+		if next, err := buf.U8Parse(); err != nil || next != 5 {
+			t.Errorf("following field = %d, %v, want 5", next, err)
+		}
+	}
+}
+
+func TestNumberParse_ZeroLength(t *testing.T) {
+	buf := &Buffer{Bytes: []byte{0x61, 0x51, 0x63, 0x01, 0x02}}
+	if v, err := buf.U32Parse(); err != nil || v != 0 {
+		t.Fatalf("U32Parse() = %d, %v", v, err)
+	}
+	if v, err := buf.I64Parse(); err != nil || v != 0 {
+		t.Fatalf("I64Parse() = %d, %v", v, err)
+	}
+	if v, err := buf.U16Parse(); err != nil || v != 0x0102 {
+		t.Fatalf("U16Parse() = %d, %v", v, err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Detect
+// ---------------------------------------------------------------------------
+
+func TestDetect(t *testing.T) {
+	r := bufio.NewReader(bytes.NewReader(append([]byte("garbage"), fixtureDZG...)))
+	ok, err := Detect(r)
+	if !ok || err != nil {
+		t.Fatalf("Detect() = %v, %v", ok, err)
+	}
+	// nothing consumed
+	if r.Buffered() < 7 {
+		t.Fatalf("Detect consumed bytes")
+	}
+	var n int
+	if err := Read(r, WithObisCallback(OctetString{}, func(*ListEntry) { n++ }),
+		WithErrorCallback(func(err error) {
+			var skipped *SkippedBytesError
+			if !errors.As(err, &skipped) || skipped.Count != 7 {
+				t.Errorf("unexpected error %v", err)
+			}
+		})); err != nil || n == 0 {
+		t.Fatalf("Read after Detect: %d entries, %v", n, err)
+	}
+
+	dsmr := []byte("/ISk5\\2MT382-1000\r\n\r\n1-0:1.8.1(123456.789*kWh)\r\n!\r\n")
+	if ok, err := Detect(bufio.NewReader(bytes.NewReader(dsmr))); ok || err != io.EOF {
+		t.Fatalf("Detect(dsmr) = %v, %v, want false, io.EOF", ok, err)
+	}
+
+	long := bytes.Repeat([]byte{0x55}, 1024)
+	if ok, err := Detect(bufio.NewReader(bytes.NewReader(long))); ok || err != nil {
+		t.Fatalf("Detect(long) = %v, %v, want false, nil", ok, err)
+	}
+}
+
+// stallingReader returns data in a single read and fails the test if read again, like a serial
+// port that hasn't received more data yet
+type stallingReader struct {
+	t    *testing.T
+	data []byte
+}
+
+func (sr *stallingReader) Read(p []byte) (int, error) {
+	if len(sr.data) == 0 {
+		sr.t.Fatal("read beyond the available data")
+	}
+	n := copy(p, sr.data)
+	sr.data = sr.data[n:]
+	return n, nil
+}
+
+func TestDetect_ReturnsEarly(t *testing.T) {
+	ok, err := Detect(bufio.NewReader(&stallingReader{t: t, data: append([]byte("xy"), fixtureDZG[:12]...)}))
+	if !ok || err != nil {
+		t.Fatalf("Detect() = %v, %v, want true, nil", ok, err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: TimeSeries ordering
+// ---------------------------------------------------------------------------
+
+func TestTimeSeries_SortByTime(t *testing.T) {
+	for _, tc := range []struct {
+		times []Time
+		order SeriesOrder
+	}{
+		{[]Time{1, 2, 3}, SERIES_ORDER_ASCENDING},
+		{[]Time{3, 2, 1}, SERIES_ORDER_DESCENDING},
+		{[]Time{2, 3, 1}, SERIES_ORDER_UNORDERED},
+		{[]Time{5}, SERIES_ORDER_ASCENDING},
+		{nil, SERIES_ORDER_ASCENDING},
+	} {
+		var series TimeSeries
+		for _, tm := range tc.times {
+			series = append(series, TimeSeriesPoint{Time: tm, Value: float64(tm) * 10})
+		}
+		if got := series.Order(); got != tc.order {
+			t.Errorf("Order() of %v = %d, want %d", tc.times, got, tc.order)
+		}
+		series.SortByTime()
+		if series.Order() != SERIES_ORDER_ASCENDING {
+			t.Errorf("SortByTime() of %v = %v", tc.times, series)
+		}
+		for _, p := range series {
+			if p.Value != float64(p.Time)*10 {
+				t.Errorf("SortByTime() of %v separated time and value: %v", tc.times, series)
+			}
+		}
+	}
+}
+
+func TestProfileSeries_NewestFirst(t *testing.T) {
+	var series TimeSeries
+	for i, v := range []uint32{1251, 1240, 1234} {
+		msgs, err := parseFrame(buildSMLFrame(smlProfileListResponse(1600001800-uint32(i)*900, v, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := msgs[0].MessageBody.Data.(GetProfileListResponse)
+		series = append(series, ProfileSeries(&resp, OctetString{1, 0, 1, 8, 0, 255})...)
+	}
+	if series.Order() != SERIES_ORDER_DESCENDING {
+		t.Fatalf("Order() = %d, want SERIES_ORDER_DESCENDING", series.Order())
+	}
+	series.SortByTime()
+	if series[0].Time != 1600000000 || series[2].Time != 1600001800 || series[0].Value > series[2].Value {
+		t.Fatalf("SortByTime() = %v", series)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Value.Raw
+// ---------------------------------------------------------------------------
+
+func TestValueParse_Raw(t *testing.T) {
+	for _, tc := range []struct {
+		data []byte
+		raw  OctetString
+	}{
+		{[]byte{0x65, 0x00, 0x01, 0x02, 0x03, 0xff}, OctetString{0x00, 0x01, 0x02, 0x03}},
+		{[]byte{0x56, 0xff, 0xff, 0xff, 0xff, 0xfe}, OctetString{0xff, 0xff, 0xff, 0xff, 0xfe}},
+		{[]byte{0x42, 0x01}, OctetString{0x01}},
+		{[]byte{0x04, 'E', 'M', 'H'}, OctetString{'E', 'M', 'H'}},
+		{append([]byte{0x81, 0x02}, bytes.Repeat([]byte{0xaa}, 16)...), bytes.Repeat([]byte{0xaa}, 16)},
+		{[]byte{0x61, 0x62}, OctetString{}},
+		{[]byte{0x01}, nil},
+	} {
+		buf := &Buffer{Bytes: tc.data}
+		v, err := buf.ValueParse()
+		if err != nil {
+			t.Errorf("ValueParse(% x) error: %v", tc.data, err)
+			continue
+		}
+		if !bytes.Equal(v.Raw, tc.raw) || (tc.raw == nil) != (v.Raw == nil) {
+			t.Errorf("ValueParse(% x).Raw = % x, want % x", tc.data, v.Raw, tc.raw)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: OBIS allowlist / denylist
+// ---------------------------------------------------------------------------
+
+func TestRead_ObisAllowAndDenylist(t *testing.T) {
+	read := func(opts ...ReadOption) (callbacks []string, listed []string) {
+		opts = append(opts,
+			WithObisCallback(OctetString{}, func(le *ListEntry) { callbacks = append(callbacks, le.ObjectName()) }),
+			WithGetListResponseCallback(func(list GetListResponse) {
+				for _, le := range list.ValList {
+					listed = append(listed, le.ObjectName())
+				}
+			}))
+		if err := Read(bufio.NewReader(bytes.NewReader(fixtureDZG)), opts...); err != nil {
+			t.Fatal(err)
+		}
+		return callbacks, listed
+	}
+
+	callbacks, listed := read(WithObisAllowlist([]OctetString{{1, 0, 1, 8}, {1, 0, 2, 8}}))
+	if fmt.Sprint(callbacks) != "[1-0:1.8.0*255 1-0:2.8.0*255]" || fmt.Sprint(listed) != fmt.Sprint(callbacks) {
+		t.Errorf("allowlist: callbacks %v, listed %v", callbacks, listed)
+	}
+
+	callbacks, listed = read(WithObisDenylist([]OctetString{{1, 0, 96}}))
+	if fmt.Sprint(callbacks) != "[1-0:1.8.0*255 1-0:2.8.0*255 1-0:16.7.0*255]" || fmt.Sprint(listed) != fmt.Sprint(callbacks) {
+		t.Errorf("denylist: callbacks %v, listed %v", callbacks, listed)
+	}
+
+	callbacks, _ = read(WithObisAllowlist([]OctetString{{1, 0, 1, 8}, {1, 0, 2, 8}}), WithObisDenylist([]OctetString{{1, 0, 2}}))
+	if fmt.Sprint(callbacks) != "[1-0:1.8.0*255]" {
+		t.Errorf("allowlist and denylist: callbacks %v", callbacks)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: escaped payload
+// ---------------------------------------------------------------------------
+
+func TestRead_EscapedPayload(t *testing.T) {
+	// list entry whose octet string value contains an escape sequence
+	entry := []byte{0x77, 0x07, 1, 0, 96, 50, 1, 1, 0x01, 0x01, 0x01, 0x01,
+		0x09, 0xaa, 0x1b, 0x1b, 0x1b, 0x1b, 0xbb, 0xcc, 0xdd, 0x01}
+	payload := smlGetListResponse(entry)
+	idx := bytes.Index(payload, escSeq)
+	// leading zero bytes are skipped by the parser and align the escape sequence
+	payload = append(make([]byte, (4-idx%4)%4), payload...)
+
+	wire := buildSMLFrame(appendEscaped(nil, payload))
+	if bytes.Count(wire, escSeq) != 4 {
+		t.Fatalf("escape sequence not escaped: % x", wire)
+	}
+
+	for _, size := range []int{16, 4096} {
+		var values []OctetString
+		var errs []error
+		err := Read(bufio.NewReaderSize(bytes.NewReader(wire), size),
+			WithObisCallback(OctetString{1, 0, 96, 50}, func(le *ListEntry) {
+				values = append(values, le.Value.DataBytes)
+			}),
+			WithErrorCallback(func(err error) { errs = append(errs, err) }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := OctetString{0xaa, 0x1b, 0x1b, 0x1b, 0x1b, 0xbb, 0xcc, 0xdd}
+		if len(values) != 1 || !bytes.Equal(values[0], want) {
+			t.Errorf("buffer size %d: got values % x, errors %v", size, values, errs)
+		}
+	}
+}
+
+func TestEncodeFile_Escaping(t *testing.T) {
+	escaped := 0
+	// shift the password of escape bytes through all alignments
+	for pad := 0; pad < 4; pad++ {
+		msg := &Message{TransactionID: OctetString{0x01}, MessageBody: MessageBody{
+			Tag: MESSAGE_OPEN_REQUEST,
+			Data: OpenRequest{ClientID: OctetString{0x01}, ReqFileID: bytes.Repeat([]byte{0x02}, pad+1),
+				Password: OctetString{0x1b, 0x1b, 0x1b, 0x1b}},
+		}}
+		file, err := EncodeFile(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Count(file, escSeq) > 3 {
+			escaped++
+		}
+		files, _ := readAllFiles(file, 4096)
+		if len(files) != 1 {
+			t.Fatalf("pad %d: read %d files", pad, len(files))
+		}
+		messages, err := parseFrame(files[0])
+		if err != nil {
+			t.Fatalf("pad %d: %v", pad, err)
+		}
+		if got := messages[0].MessageBody.Data.(OpenRequest).Password; !bytes.Equal(got, escSeq) {
+			t.Fatalf("pad %d: password % x", pad, got)
+		}
+	}
+	if escaped != 1 {
+		t.Fatalf("escape sequence escaped for %d alignments, want 1", escaped)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Direction
+// ---------------------------------------------------------------------------
+
+func TestListEntryDirection(t *testing.T) {
+	for _, tc := range []struct {
+		le   ListEntry
+		want Direction
+	}{
+		{ListEntry{ObjName: OctetString{1, 0, 1, 8, 0, 255}}, DIRECTION_IMPORT},
+		{ListEntry{ObjName: OctetString{1, 0, 2, 8, 1, 255}}, DIRECTION_EXPORT},
+		{ListEntry{ObjName: OctetString{1, 0, 16, 7, 0, 255}}, DIRECTION_UNKNOWN},
+		{ListEntry{ObjName: OctetString{1, 0, 16, 7, 0, 255}, hasStatus: true, status: 0x0182}, DIRECTION_IMPORT},
+		{ListEntry{ObjName: OctetString{1, 0, 16, 7, 0, 255}, hasStatus: true, status: 0x01a2}, DIRECTION_EXPORT},
+		{ListEntry{ObjName: OctetString{1, 0, 1, 8, 0, 255}, hasStatus: true, status: 0x01a2}, DIRECTION_IMPORT},
+		{ListEntry{ObjName: OctetString{1, 2}}, DIRECTION_UNKNOWN},
+	} {
+		if got := tc.le.Direction(); got != tc.want {
+			t.Errorf("Direction() of %s (status %x) = %s, want %s", tc.le.ObjectName(), tc.le.status, got, tc.want)
+		}
+	}
+}
+
+func TestListEntryParse_StatusPresence(t *testing.T) {
+	withStatus := []byte{0x77, 0x07, 1, 0, 16, 7, 0, 255, 0x63, 0x01, 0xa2, 0x01, 0x62, UNIT_WATT, 0x52, 0x00, 0x53, 0x00, 0x10, 0x01}
+	le, err := ListEntryParse(&Buffer{Bytes: withStatus})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !le.hasStatus || le.Direction() != DIRECTION_EXPORT {
+		t.Errorf("with status: hasStatus = %v, Direction() = %s", le.hasStatus, le.Direction())
+	}
+
+	withoutStatus := smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 16)
+	if le, err = ListEntryParse(&Buffer{Bytes: withoutStatus}); err != nil {
+		t.Fatal(err)
+	}
+	if le.hasStatus || le.Direction() != DIRECTION_UNKNOWN {
+		t.Errorf("without status: hasStatus = %v, Direction() = %s", le.hasStatus, le.Direction())
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ParseBytes in place
+// ---------------------------------------------------------------------------
+
+func TestParseBytes_MatchesRead(t *testing.T) {
+	escapedEntry := []byte{0x77, 0x07, 1, 0, 96, 50, 1, 1, 0x01, 0x01, 0x01, 0x01,
+		0x09, 0xaa, 0x1b, 0x1b, 0x1b, 0x1b, 0xbb, 0xcc, 0xdd, 0x01}
+	escapedPayload := smlGetListResponse(escapedEntry)
+	escapedPayload = append(make([]byte, (4-bytes.Index(escapedPayload, escSeq)%4)%4), escapedPayload...)
+	escaped := buildSMLFrame(appendEscaped(nil, escapedPayload))
+
+	inputs := map[string][]byte{
+		"DZG":       fixtureDZG,
+		"EMH":       fixtureEMH,
+		"HOLLEY":    fixtureHOLLEY,
+		"ISKRA":     fixtureISKRA,
+		"ITRON":     fixtureITRON,
+		"garbage":   append(append([]byte{0x01, 0x1b, 0x1b, 0x55}, fixtureITRON...), 0x1b, 0x1b),
+		"escaped":   append(append([]byte(nil), escaped...), fixtureDZG...),
+		"truncated": append(append([]byte(nil), fixtureDZG...), fixtureDZG[:40]...),
+		"tooLong":   append(append([]byte(nil), startSeq...), make([]byte, 600)...),
+		"unknown":   append(append(append([]byte(nil), startSeq...), 0x1b, 0x1b, 0x1b, 0x1b, 0x02, 0, 0, 0), fixtureDZG...),
+	}
+
+	collect := func(parse func(opts ...ReadOption) error) string {
+		var out []string
+		err := parse(
+			WithObisCallback(OctetString{}, func(le *ListEntry) { out = append(out, le.String()) }),
+			WithErrorCallback(func(err error) { out = append(out, "error: "+err.Error()) }))
+		if err != nil {
+			out = append(out, "returned: "+err.Error())
+		}
+		return strings.Join(out, "\n")
+	}
+
+	for name, data := range inputs {
+		orig := append([]byte(nil), data...)
+		want := collect(func(opts ...ReadOption) error {
+			return Read(bufio.NewReader(bytes.NewReader(data)), opts...)
+		})
+		got := collect(func(opts ...ReadOption) error {
+			return ParseBytes(data, opts...)
+		})
+		if got != want {
+			t.Errorf("%s: ParseBytes\n%s\nRead\n%s", name, got, want)
+		}
+		if !bytes.Equal(data, orig) {
+			t.Errorf("%s: ParseBytes modified its input", name)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: attention numbers
+// ---------------------------------------------------------------------------
+
+func TestAttentionResponse_Description(t *testing.T) {
+	for _, tc := range []struct {
+		number      OctetString
+		code        string
+		description string
+		isError     bool
+	}{
+		{OctetString{0x81, 0x81, 0xc7, 0xc7, 0xfd, 0x00}, "81-81:C7.C7.FD*00", "ok", false},
+		{OctetString{0x81, 0x81, 0xc7, 0xc7, 0xfe, 0x02}, "81-81:C7.C7.FE*02", "insufficient authentication", true},
+		{OctetString{0x81, 0x81, 0xc7, 0xc7, 0xfe, 0x7f}, "81-81:C7.C7.FE*7F", "unknown attention number 81-81:C7.C7.FE*7F", true},
+		{OctetString{0x01, 0x02}, "0102", "unknown attention number 0102", false},
+	} {
+		msg := &AttentionResponse{AttentionNumber: tc.number}
+		if got := msg.AttentionCode(); got != tc.code {
+			t.Errorf("AttentionCode() = %q, want %q", got, tc.code)
+		}
+		if got := msg.Description(); got != tc.description {
+			t.Errorf("Description() of %s = %q, want %q", tc.code, got, tc.description)
+		}
+		if got := msg.IsError(); got != tc.isError {
+			t.Errorf("IsError() of %s = %v", tc.code, got)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: compound values
+// ---------------------------------------------------------------------------
+
+func TestListParse_CompoundValue(t *testing.T) {
+	// cosem value: list of scaler/unit and value
+	compound := []byte{0x72, 0x72, 0x52, 0xfd, 0x62, UNIT_VOLT, 0x63, 0x5a, 0xa0}
+	entry := append([]byte{0x77, 0x07, 1, 0, 32, 7, 0, 255, 0x01, 0x01, 0x01, 0x01}, compound...)
+	entry = append(entry, 0x01)
+	data := append([]byte{0x72}, entry...)
+	data = append(data, smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, 7)...)
+
+	list, err := ListParse(&Buffer{Bytes: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("got %d entries, want 2", len(list))
+	}
+	v := list[0].Value
+	if !v.IsCompound() || !bytes.Equal(v.Raw, compound[1:]) {
+		t.Errorf("compound value %+v", v)
+	}
+	if list[0].IsNumeric() || list[0].Float() != 0 {
+		t.Errorf("compound value must not be numeric")
+	}
+	if list[1].Value.DataInt != 7 || list[1].Value.IsCompound() {
+		t.Errorf("entry after compound value misparsed: %+v", list[1].Value)
+	}
+
+	other := v
+	other.Raw = OctetString{0x72, 0x52, 0xfd, 0x62, UNIT_VOLT, 0x63, 0x5a, 0xa1}
+	if v.Equal(other) || !v.Equal(v) {
+		t.Errorf("Equal() must compare raw bytes of compound values")
+	}
+}
+
+func TestListEntry_ValueList(t *testing.T) {
+	// value as list of two numbers, followed by a regular entry
+	entry := []byte{0x77, 0x07, 1, 0, 128, 7, 0, 255, 0x01, 0x01, 0x62, UNIT_WATT, 0x52, 0xff,
+		0x72, 0x52, 0xfb, 0x63, 0x01, 0x00, 0x01}
+	frame := buildSMLFrame(smlGetListResponse(entry,
+		smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, 7)))
+
+	got := map[string]*ListEntry{}
+	if err := Read(bufio.NewReader(bytes.NewReader(frame)),
+		WithObisCallback(OctetString{}, func(le *ListEntry) { got[le.ObjectName()] = le })); err != nil {
+		t.Fatal(err)
+	}
+	pair := got["1-0:128.7.0*255"]
+	if pair == nil || fmt.Sprint(pair.ValueList()) != "[-0.5 25.6]" {
+		t.Fatalf("pair entry %v", pair)
+	}
+	if s := pair.ValueString(); s != "(-0.5, 25.6)" {
+		t.Errorf("ValueString() = %q", s)
+	}
+	if le := got["1-0:1.8.0*255"]; le == nil || le.Float() != 7 || le.ValueList() != nil {
+		t.Errorf("entry after pair %v", le)
+	}
+
+	// the nested list of a cosem value isn't a number
+	cosem := &ListEntry{Value: Value{Typ: OCTET_TYPE_LIST,
+		Raw: OctetString{0x72, 0x52, 0xfd, 0x62, UNIT_VOLT, 0x63, 0x5a, 0xa0, 0x01}}}
+	values := cosem.ValueList()
+	if len(values) != 3 || !math.IsNaN(values[0]) || values[1] != 23200 || !math.IsNaN(values[2]) {
+		t.Errorf("ValueList() = %v", values)
+	}
+}
+
+func TestValueParse_TruncatedCompound(t *testing.T) {
+	if _, err := (&Buffer{Bytes: []byte{0x72, 0x62, 0x01}}).ValueParse(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: PowerFromEnergy
+// ---------------------------------------------------------------------------
+
+func TestPowerFromEnergy(t *testing.T) {
+	entry := func(obis OctetString, unit uint8, scaler int8, value int64) *ListEntry {
+		return &ListEntry{ObjName: obis, Unit: unit, scaler: scaler, Value: Value{Typ: OCTET_TYPE_UNSIGNED | TYPE_NUMBER_32, DataInt: value}}
+	}
+	import180 := OctetString{1, 0, 1, 8, 0, 255}
+
+	// 25 Wh in 60 s = 1500 W
+	p, err := PowerFromEnergy(entry(import180, UNIT_WATT_HOUR, -1, 10000), entry(import180, UNIT_WATT_HOUR, -1, 10250), time.Minute)
+	if err != nil || math.Abs(p-1500) > 1e-9 {
+		t.Fatalf("PowerFromEnergy() = %v, %v, want 1500", p, err)
+	}
+
+	for name, tc := range map[string]struct {
+		prev, cur *ListEntry
+		dt        time.Duration
+	}{
+		"different codes": {entry(import180, UNIT_WATT_HOUR, 0, 1), entry(OctetString{1, 0, 2, 8, 0, 255}, UNIT_WATT_HOUR, 0, 2), time.Second},
+		"not energy":      {entry(OctetString{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 1), entry(OctetString{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 2), time.Second},
+		"wrong unit":      {entry(import180, UNIT_WATT, 0, 1), entry(import180, UNIT_WATT, 0, 2), time.Second},
+		"zero interval":   {entry(import180, UNIT_WATT_HOUR, 0, 1), entry(import180, UNIT_WATT_HOUR, 0, 2), 0},
+		"decreasing":      {entry(import180, UNIT_WATT_HOUR, 0, 2), entry(import180, UNIT_WATT_HOUR, 0, 1), time.Second},
+		"non-numeric":     {&ListEntry{ObjName: import180, Unit: UNIT_WATT_HOUR}, entry(import180, UNIT_WATT_HOUR, 0, 1), time.Second},
+	} {
+		if _, err := PowerFromEnergy(tc.prev, tc.cur, tc.dt); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Streaming with bounded memory
+// ---------------------------------------------------------------------------
+
+// repeatReader yields data n times without holding more than a single copy
+type repeatReader struct {
+	data []byte
+	n    int
+	off  int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, io.EOF
+	}
+	copied := copy(p, r.data[r.off:])
+	r.off += copied
+	if r.off == len(r.data) {
+		r.off = 0
+		r.n--
+	}
+	return copied, nil
+}
+
+func TestRead_ConstantMemory(t *testing.T) {
+	frames := 500000
+	if testing.Short() {
+		frames = 20000
+	}
+	frame := buildSMLFrame(smlGetListResponse(
+		smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 123456),
+		smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 420),
+	))
+	src := &repeatReader{data: frame, n: frames}
+
+	heapAlloc := func() uint64 {
+		var stats runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		return stats.HeapAlloc
+	}
+
+	var base, peak uint64
+	files := 0
+	err := Read(bufio.NewReader(src), WithGetListResponseCallback(func(GetListResponse) {
+		files++
+		if files%(frames/10) != 0 {
+			return
+		}
+		heap := heapAlloc()
+		if base == 0 {
+			base = heap
+		}
+		if heap > peak {
+			peak = heap
+		}
+	}), WithErrorCallback(func(err error) {
+		t.Fatalf("unexpected error: %v", err)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files != frames {
+		t.Fatalf("got %d files, want %d", files, frames)
+	}
+	t.Logf("streamed %d bytes, heap %d..%d bytes", len(frame)*frames, base, peak)
+	if peak > base+256<<10 {
+		t.Errorf("heap grew from %d to %d bytes while streaming", base, peak)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithObisFallback
+// ---------------------------------------------------------------------------
+
+func TestRead_WithObisFallback(t *testing.T) {
+	var specific, all, fallback []string
+	record := func(names *[]string) func(*ListEntry) {
+		return func(le *ListEntry) { *names = append(*names, le.ObjectName()) }
+	}
+	err := Read(bufio.NewReader(bytes.NewReader(fixtureDZG)),
+		WithObisCallback(OctetString{1, 0, 1, 8, 0}, record(&specific)),
+		WithObisPatternCallback(ObisPattern{1, 0, 16, OBIS_WILDCARD}, record(&specific)),
+		WithObisCallback(nil, record(&all)),
+		WithObisFallback(record(&fallback)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range fallback {
+		for _, s := range specific {
+			if name == s {
+				t.Errorf("fallback called for %s, which has a specific callback", name)
+			}
+		}
+	}
+	if len(specific) != 2 {
+		t.Errorf("specific callbacks called for %v, want 1.8.0 and 16.7.0", specific)
+	}
+	if len(specific)+len(fallback) != len(all) {
+		t.Errorf("%d specific + %d fallback calls, want %d", len(specific), len(fallback), len(all))
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ListName
+// ---------------------------------------------------------------------------
+
+func TestListNameKind(t *testing.T) {
+	for _, tc := range []struct {
+		name OctetString
+		kind ListKind
+	}{
+		{OctetString{1, 0, 99, 1, 0, 255}, LIST_KIND_CURRENT},
+		{OctetString{0, 0, 98, 1, 3, 255}, LIST_KIND_HISTORICAL},
+		{OctetString{1, 0, 99, 98, 0, 255}, LIST_KIND_EVENTS},
+		{OctetString{1, 0, 98, 10, 255, 255}, LIST_KIND_UNKNOWN},
+		{nil, LIST_KIND_UNKNOWN},
+	} {
+		if kind := ListName(tc.name).Kind(); kind != tc.kind {
+			t.Errorf("Kind() of % x = %v, want %v", tc.name, kind, tc.kind)
+		}
+		list := GetListResponse{ListName: tc.name}
+		if kind := list.ListKind(); kind != tc.kind {
+			t.Errorf("ListKind() of % x = %v, want %v", tc.name, kind, tc.kind)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ReadRecords
+// ---------------------------------------------------------------------------
+
+func TestReadRecords(t *testing.T) {
+	frame := buildSMLFrame(smlGetListResponse(
+		smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 420),
+		smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 123456),
+	))
+	data := append(append([]byte{}, frame...), frame...)
+	schema := []OctetString{{1, 0, 1, 8, 0}, {1, 0, 2, 8, 0}, {1, 0, 16, 7, 0}}
+
+	records, err := ReadRecords(context.Background(), bufio.NewReader(bytes.NewReader(data)), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for record := range records.C {
+		n++
+		if len(record) != len(schema) {
+			t.Fatalf("record has %d columns, want %d", len(record), len(schema))
+		}
+		if record[0] != 12345.6 || !math.IsNaN(record[1]) || record[2] != 420 {
+			t.Errorf("record = %v, want [12345.6 NaN 420]", record)
+		}
+	}
+	if n != 2 {
+		t.Errorf("got %d records, want 2", n)
+	}
+	if err := records.Err(); err != nil {
+		t.Errorf("Err() = %v at the end of the reader", err)
+	}
+
+	if _, err := ReadRecords(context.Background(), bufio.NewReader(bytes.NewReader(data)), nil); err == nil {
+		t.Error("expected error for empty schema")
+	}
+}
+
+func TestReadRecords_ReadError(t *testing.T) {
+	errBroken := errors.New("broken")
+	r := io.MultiReader(bytes.NewReader(buildSMLFrame(smlGetListResponse(
+		smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, 1)))), iotest.ErrReader(errBroken))
+	records, err := ReadRecords(context.Background(), bufio.NewReader(r), []OctetString{{1, 0, 1, 8, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for range records.C {
+		n++
+	}
+	if n != 1 || !errors.Is(records.Err(), errBroken) {
+		t.Errorf("got %d records, Err() = %v", n, records.Err())
+	}
+}
+
+func TestReadRecords_Cancel(t *testing.T) {
+	frame := buildSMLFrame(smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, 1)))
+	data := bytes.Repeat(frame, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	records, err := ReadRecords(ctx, bufio.NewReader(bytes.NewReader(data)), []OctetString{{1, 0, 1, 8, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-records.C
+	// stop draining, the background reader must not block on sending
+	cancel()
+	for range records.C {
+	}
+	if !errors.Is(records.Err(), context.Canceled) {
+		t.Errorf("Err() = %v, want context.Canceled", records.Err())
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Skipping unknown message types
+// ---------------------------------------------------------------------------
+
+func TestParseFile_SkipsUnknownMessage(t *testing.T) {
+	list := smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 123456))
+	vendor := smlMessage(0x0000ABCD, []byte{0x72, 0x62, 0x01, 0x73, 0x03, 0x12, 0x34, 0x42, 0x01, 0x01})
+	frame := buildSMLFrame(append(append(append([]byte{}, list...), vendor...), list...))
+
+	var entries int
+	var errs []error
+	err := Read(bufio.NewReader(bytes.NewReader(frame)),
+		WithObisCallback(OctetString{1, 0, 1, 8, 0}, func(*ListEntry) { entries++ }),
+		WithErrorCallback(func(err error) { errs = append(errs, err) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if entries != 2 {
+		t.Errorf("got %d entries, want 2 (from the lists around the unknown message)", entries)
+	}
+
+	messages, err := parseFrame(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 || messages[1].MessageBody.Tag != 0x0000ABCD || messages[1].MessageBody.Data != nil {
+		t.Errorf("unexpected messages: %+v", messages)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: OpenResponse accessors
+// ---------------------------------------------------------------------------
+
+func TestOpenResponse_VersionAndCodepage(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		data     []byte
+		version  uint8
+		codepage string
+	}{
+		{"present", []byte{0x76, 0x06, 'U', 'T', 'F', '-', '8', 0x01, 0x03, 0x01, 0x02, 0x03, 0xaa, 0xbb, 0x01, 0x62, 0x02}, 2, "UTF-8"},
+		{"skipped", []byte{0x76, 0x01, 0x01, 0x03, 0x01, 0x02, 0x03, 0xaa, 0xbb, 0x01, 0x01}, 1, "ASCII"},
+	} {
+		msg, err := OpenResponseParse(&Buffer{Bytes: tc.data})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if v := msg.SMLVersion(); v != tc.version {
+			t.Errorf("%s: SMLVersion() = %d, want %d", tc.name, v, tc.version)
+		}
+		if cp := msg.CodepageName(); cp != tc.codepage {
+			t.Errorf("%s: CodepageName() = %q, want %q", tc.name, cp, tc.codepage)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: GetListResponse.Find
+// ---------------------------------------------------------------------------
+
+func TestGetListResponse_Find(t *testing.T) {
+	var list GetListResponse
+	err := Read(bufio.NewReader(bytes.NewReader(fixtureEMH)), WithGetListResponseCallback(func(msg GetListResponse) {
+		list = msg
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, le := range list.Find(OctetString{1, 0, 1, 8}) {
+		if _, _, c, d, _, _, _ := le.ObisFields(); c != 1 || d != 8 {
+			t.Errorf("Find(1.8) returned %s", le.ObjectName())
+		}
+	}
+	if n := len(list.Find(OctetString{1, 0, 1, 8})); n != 3 {
+		t.Errorf("Find(1.8) returned %d entries, want 1.8.0, 1.8.1 and 1.8.2", n)
+	}
+	if n := len(list.Find(nil)); n != len(list.ValList) {
+		t.Errorf("Find(nil) returned %d entries, want %d", n, len(list.ValList))
+	}
+	if entries := list.Find(OctetString{1, 0, 99}); entries == nil || len(entries) != 0 {
+		t.Errorf("Find(99) = %v, want empty slice", entries)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ExtractFiles
+// ---------------------------------------------------------------------------
+
+func TestExtractFiles(t *testing.T) {
+	dzg := ExtractFiles(fixtureDZG)
+	emh := ExtractFiles(fixtureEMH)
+	if len(dzg) != 1 || len(emh) != 12 {
+		t.Fatalf("got %d DZG and %d EMH files, want 1 and 12", len(dzg), len(emh))
+	}
+
+	corrupt := append([]byte(nil), dzg[0]...)
+	corrupt[len(corrupt)-1] ^= 0xff
+
+	var capture []byte
+	capture = append(capture, 0x00, 0x42, 0x1b)
+	capture = append(capture, dzg[0]...)
+	capture = append(capture, corrupt...)
+	capture = append(capture, emh[0][:40]...) // truncated
+	for _, file := range emh {
+		capture = append(capture, file...)
+	}
+	capture = append(capture, emh[0][:20]...)
+
+	files := ExtractFiles(capture)
+	want := append([][]byte{dzg[0]}, emh...)
+	if len(files) != len(want) {
+		t.Fatalf("got %d files, want %d", len(files), len(want))
+	}
+	for i := range files {
+		if !bytes.Equal(files[i], want[i]) {
+			t.Errorf("file %d differs", i)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Status widths
+// ---------------------------------------------------------------------------
+
+func TestListEntryParse_StatusWidths(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		status  []byte
+		want    int64
+		raw     OctetString
+		present bool
+	}{
+		{"u8", []byte{0x62, 0x82}, 0x82, OctetString{0x82}, true},
+		{"u32", []byte{0x65, 0x00, 0x1c, 0x01, 0x04}, 0x1c0104, OctetString{0x00, 0x1c, 0x01, 0x04}, true},
+		{"u64 with 6 bytes", []byte{0x67, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04}, 0x01020304, OctetString{0x00, 0x00, 0x01, 0x02, 0x03, 0x04}, true},
+		{"octet string", []byte{0x03, 0x01, 0xa2}, 0x01a2, OctetString{0x01, 0xa2}, true},
+		{"skipped", []byte{0x01}, 0, nil, false},
+	} {
+		data := []byte{0x77, 0x07, 0x01, 0x00, 0x01, 0x08, 0x00, 0xff}
+		data = append(data, tc.status...)
+		data = append(data, 0x01, 0x62, 0x1e, 0x52, 0xff, 0x65, 0x00, 0x01, 0xe2, 0x40, 0x01)
+
+		le, err := ListEntryParse(&Buffer{Bytes: data})
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if status, ok := le.Status(); status != tc.want || ok != tc.present {
+			t.Errorf("%s: Status() = %x, %v, want %x, %v", tc.name, status, ok, tc.want, tc.present)
+		}
+		if !bytes.Equal(le.StatusRaw(), tc.raw) {
+			t.Errorf("%s: StatusRaw() = % x, want % x", tc.name, le.StatusRaw(), tc.raw)
+		}
+		if le.Unit != UNIT_WATT_HOUR || le.Value.DataInt != 123456 {
+			t.Errorf("%s: fields after status misread: unit %d, value %d", tc.name, le.Unit, le.Value.DataInt)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Watch
+// ---------------------------------------------------------------------------
+
+func TestWatch(t *testing.T) {
+	var data []byte
+	for i, power := range []uint32{420, 420, 500} {
+		data = append(data, buildSMLFrame(smlGetListResponse(
+			smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 123456),
+			smlListEntry([]byte{1, 0, 2, 8, 0, 255}, UNIT_WATT_HOUR, -1, uint32(i)),
+			smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, power),
+		))...)
+	}
+
+	events, err := Watch(context.Background(), bufio.NewReader(bytes.NewReader(data)), []OctetString{{1, 0, 1, 8, 0}, {1, 0, 16, 7, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []ChangeEvent
+	for ev := range events.C {
+		got = append(got, ev)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d events, want 1: %+v", len(got), got)
+	}
+	if ev := got[0]; ev.Obis != "1-0:16.7.0*255" || ev.Old != 420 || ev.New != 500 || ev.Time.IsZero() {
+		t.Errorf("unexpected event %+v", ev)
+	}
+
+	if err := events.Err(); err != nil {
+		t.Errorf("Err() = %v at the end of the reader", err)
+	}
+
+	if _, err := Watch(context.Background(), bufio.NewReader(bytes.NewReader(data)), nil); err == nil {
+		t.Error("expected error without codes")
+	}
+
+	// a consumer that stops draining cancels the watch
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err = Watch(ctx, bufio.NewReader(bytes.NewReader(bytes.Repeat(data, 3))), []OctetString{{1, 0, 16, 7, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-events.C
+	cancel()
+	for range events.C {
+	}
+	if !errors.Is(events.Err(), context.Canceled) {
+		t.Errorf("Err() = %v, want context.Canceled", events.Err())
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithMaxConsecutiveErrors
+// ---------------------------------------------------------------------------
+
+func TestRead_WithMaxConsecutiveErrors(t *testing.T) {
+	good := buildSMLFrame(smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 123456)))
+	bad := buildSMLFrame([]byte{0x72, 0x62, 0x01, 0x62, 0x01})
+	stream := func(frames ...[]byte) *bufio.Reader {
+		return bufio.NewReader(bytes.NewReader(bytes.Join(frames, nil)))
+	}
+
+	var files int
+	count := WithGetListResponseCallback(func(GetListResponse) { files++ })
+
+	err := Read(stream(good, bad, bad, bad, good), WithMaxConsecutiveErrors(3), count)
+	if !errors.Is(err, ErrTooManyErrors) {
+		t.Errorf("Read() = %v, want ErrTooManyErrors", err)
+	}
+	if files != 1 {
+		t.Errorf("got %d files before failing, want 1", files)
+	}
+
+	files = 0
+	if err := Read(stream(bad, bad, good, bad, bad, good), WithMaxConsecutiveErrors(3), count); err != nil {
+		t.Errorf("Read() = %v, want nil as the count is reset by valid files", err)
+	}
+	if files != 2 {
+		t.Errorf("got %d files, want 2", files)
+	}
+
+	if err := ParseBytes(bytes.Join([][]byte{bad, bad, bad}, nil), WithMaxConsecutiveErrors(3)); !errors.Is(err, ErrTooManyErrors) {
+		t.Errorf("ParseBytes() = %v, want ErrTooManyErrors", err)
+	}
+	if err := Read(stream(bad, bad, bad, bad)); err != nil {
+		t.Errorf("Read() without budget = %v, want nil", err)
+	}
+}
+
+// garbageReader is an endless stream without begin sequence, like a serial port at a wrong baud rate
+type garbageReader struct{}
+
+func (garbageReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0x55
+	}
+	return len(p), nil
+}
+
+func TestRead_WithMaxConsecutiveErrors_Garbage(t *testing.T) {
+	var skipped, noBegin int
+	err := Read(bufio.NewReader(garbageReader{}), WithMaxConsecutiveErrors(3),
+		WithErrorCallback(func(err error) {
+			var skippedErr *SkippedBytesError
+			if errors.As(err, &skippedErr) {
+				skipped += skippedErr.Count
+			}
+			if errors.Is(err, ErrNoBeginSequence) {
+				noBegin++
+			}
+		}))
+	if !errors.Is(err, ErrTooManyErrors) {
+		t.Fatalf("Read() = %v, want ErrTooManyErrors", err)
+	}
+	if noBegin != 3 || skipped != 3*maxFileSize {
+		t.Errorf("got %d ErrNoBeginSequence and %d skipped bytes, want 3 and %d", noBegin, skipped, 3*maxFileSize)
+	}
+
+	err = Read(bufio.NewReader(garbageReader{}), WithMaxConsecutiveErrors(2), WithParseWorkers(2))
+	if !errors.Is(err, ErrTooManyErrors) {
+		t.Fatalf("Read() with workers = %v, want ErrTooManyErrors", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ToMeterMessage
+// ---------------------------------------------------------------------------
+
+func TestToMeterMessage(t *testing.T) {
+	var msg MeterMessage
+	err := Read(bufio.NewReader(bytes.NewReader(fixtureDZG)), WithGetListResponseCallback(func(list GetListResponse) {
+		msg = ToMeterMessage(&list)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.ServerID == "" || strings.Trim(msg.ServerID, "0123456789abcdef") != "" {
+		t.Errorf("ServerID = %q, want hex", msg.ServerID)
+	}
+	var found bool
+	for _, r := range msg.Readings {
+		if r.Obis == "1-0:1.8.0*255" {
+			found = true
+			if r.Unit != "Wh" || r.Value <= 0 {
+				t.Errorf("unexpected reading %+v", r)
+			}
+		}
+		if r.Obis == "1-0:96.50.1*1" {
+			t.Errorf("non-numeric entry %s included", r.Obis)
+		}
+	}
+	if !found {
+		t.Errorf("1.8.0 missing in %+v", msg.Readings)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Multi-byte TL lengths
+// ---------------------------------------------------------------------------
+
+func TestOctetStringParse_MultiByteTL(t *testing.T) {
+	for _, tc := range []struct {
+		size int
+		tl   []byte
+	}{
+		{20, []byte{0x81, 0x06}},
+		{130, []byte{0x88, 0x04}},
+		{300, []byte{0x81, 0x82, 0x0f}},
+	} {
+		str := bytes.Repeat([]byte{0xab}, tc.size)
+		data := appendOctetString(nil, str)
+		if !bytes.HasPrefix(data, tc.tl) {
+			t.Errorf("%d bytes: encoded TL % x, want % x", tc.size, data[:len(tc.tl)], tc.tl)
+		}
+		data = append(data, 0x62, 0x2a)
+
+		buf := &Buffer{Bytes: data}
+		got, err := buf.OctetStringParse()
+		if err != nil {
+			t.Errorf("%d bytes: %v", tc.size, err)
+			continue
+		}
+		if !bytes.Equal(got, str) {
+			t.Errorf("%d bytes: got %d bytes", tc.size, len(got))
+		}
+		if next, err := buf.U8Parse(); err != nil || next != 42 {
+			t.Errorf("%d bytes: following element = %d, %v, want 42", tc.size, next, err)
+		}
+	}
+
+	// length exceeding the data
+	if _, err := (&Buffer{Bytes: []byte{0x81, 0x06, 0xab}}).OctetStringParse(); err == nil {
+		t.Error("expected error for truncated octet string")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithTimezone
+// ---------------------------------------------------------------------------
+
+func TestRead_WithTimezone(t *testing.T) {
+	// GetListResponse with actSensorTime 2021-01-01 00:00:00 UTC as timestamp
+	data := []byte{0x77, 0x01, 0x03, 0x01, 0x02, 0x01, 0x72, 0x62, 0x02, 0x65, 0x5f, 0xee, 0x66, 0x00, 0x71}
+	data = append(data, smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 123456)...)
+	data = append(data, 0x01, 0x01)
+	frame := buildSMLFrame(smlMessage(MESSAGE_GET_LIST_RESPONSE, data))
+
+	berlin := time.FixedZone("CET", 3600)
+	for _, tc := range []struct {
+		opts []ReadOption
+		want string
+	}{
+		{nil, "2021-01-01T00:00:00Z"},
+		{[]ReadOption{WithTimezone(berlin)}, "2021-01-01T01:00:00+01:00"},
+	} {
+		var got string
+		opts := append(tc.opts, WithGetListResponseCallback(func(list GetListResponse) {
+			sensorTime, kind := list.SensorTime()
+			if kind != TIME_KIND_TIMESTAMP {
+				t.Errorf("kind = %v, want timestamp", kind)
+			}
+			got = sensorTime.Format(time.RFC3339)
+		}))
+		if err := Read(bufio.NewReader(bytes.NewReader(frame)), opts...); err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("SensorTime() = %s, want %s", got, tc.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: FrameScanner
+// ---------------------------------------------------------------------------
+
+func TestFrameScanner_Feed(t *testing.T) {
+	var want int
+	err := Read(bufio.NewReader(bytes.NewReader(fixtureEMH)), WithRawFrameCallback(func(frame []byte) {
+		msgs, err := parseFileWith(frame, parseConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want += len(msgs)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, chunk := range []int{1, 7, 100, len(fixtureEMH)} {
+		scanner, err := NewFrameScanner(make([]byte, 512))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got int
+		data := fixtureEMH
+		for len(data) > 0 {
+			piece := data
+			if len(piece) > chunk {
+				piece = piece[:chunk]
+			}
+			msgs, n, err := scanner.Feed(piece)
+			if err != nil {
+				t.Fatalf("chunk %d: %v", chunk, err)
+			}
+			if n == 0 {
+				t.Fatalf("chunk %d: no progress", chunk)
+			}
+			got += len(msgs)
+			data = data[n:]
+		}
+		if got != want {
+			t.Errorf("chunk %d: got %d messages, want %d", chunk, got, want)
+		}
+	}
+
+	if _, err := NewFrameScanner(make([]byte, 64)); err == nil {
+		t.Error("expected error for small buffer")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Maximum demand
+// ---------------------------------------------------------------------------
+
+func TestListEntry_MaxDemand(t *testing.T) {
+	// 1-0:1.6.0 with valTime 2021-01-01 00:00:00 UTC as timestamp, 12.345 kW
+	entry := []byte{0x77, 0x07, 0x01, 0x00, 0x01, 0x06, 0x00, 0xff, 0x01,
+		0x72, 0x62, 0x02, 0x65, 0x5f, 0xee, 0x66, 0x00,
+		0x62, UNIT_WATT, 0x52, 0x00, 0x65, 0x00, 0x00, 0x30, 0x39, 0x01}
+	frame := buildSMLFrame(smlGetListResponse(entry, smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 123456)))
+
+	var found int
+	err := Read(bufio.NewReader(bytes.NewReader(frame)), WithObisCallback(nil, func(le *ListEntry) {
+		value, at, ok := le.MaxDemand()
+		if le.ObjectName() != "1-0:1.6.0*255" {
+			if ok {
+				t.Errorf("MaxDemand() ok for %s", le.ObjectName())
+			}
+			return
+		}
+		found++
+		if !ok || value != 12345 || !at.Equal(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("MaxDemand() = %v, %v, %v", value, at, ok)
+		}
+		if _, kind := le.ValTime(); kind != TIME_KIND_TIMESTAMP {
+			t.Errorf("ValTime() kind = %v, want timestamp", kind)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != 1 {
+		t.Errorf("1.6.0 found %d times, want 1", found)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Parsing deframed payloads
+// ---------------------------------------------------------------------------
+
+func TestParsePayload(t *testing.T) {
+	payload := smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 123456))
+	padded := append(append([]byte{}, payload...), 0x00, 0x00)
+
+	messages, err := ParseMessages(padded)
+	if err != nil || len(messages) != 1 || messages[0].MessageBody.Tag != MESSAGE_GET_LIST_RESPONSE {
+		t.Fatalf("ParseMessages() = %v, %v", messages, err)
+	}
+
+	var values []float64
+	err = ParsePayload(padded, WithObisCallback(OctetString{1, 0, 1, 8, 0}, func(le *ListEntry) {
+		values = append(values, le.Float())
+	}))
+	if err != nil || len(values) != 1 || math.Abs(values[0]-12345.6) > 1e-9 {
+		t.Errorf("ParsePayload() = %v, values %v", err, values)
+	}
+
+	var errs []error
+	_ = ParsePayload(payload[:10], WithErrorCallback(func(err error) { errs = append(errs, err) }))
+	if _, err := ParseMessages(payload[:10]); err == nil || len(errs) != 1 {
+		t.Errorf("truncated payload: ParseMessages() = %v, reported errors %v", err, errs)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ReadWithStats
+// ---------------------------------------------------------------------------
+
+func TestReadWithStats(t *testing.T) {
+	good := buildSMLFrame(smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 123456)))
+	bad := buildSMLFrame([]byte{0x72, 0x62, 0x01, 0x62, 0x01})
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want ReadStats
+	}{
+		{"clean", bytes.Join([][]byte{good, bad, good}, nil), ReadStats{2, 1, int64(2*len(good) + len(bad)), false}},
+		{"garbage and truncated", bytes.Join([][]byte{{0x42, 0x42, 0x42}, good, good[:30]}, nil), ReadStats{1, 0, int64(3 + len(good) + 30), true}},
+		{"escape at end", bytes.Join([][]byte{good, {0x1b, 0x1b}}, nil), ReadStats{1, 0, int64(len(good) + 2), true}},
+		{"empty", nil, ReadStats{}},
+	} {
+		stats, err := ReadWithStats(bufio.NewReader(bytes.NewReader(tc.data)))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if stats != tc.want {
+			t.Errorf("%s: stats = %+v, want %+v", tc.name, stats, tc.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithParseWorkers
+// ---------------------------------------------------------------------------
+
+func TestRead_WithParseWorkers(t *testing.T) {
+	var data []byte
+	for _, fixture := range benchmarkFixtures {
+		data = append(data, fixture.data...)
+	}
+	data = append(data, 0x1b, 0x1b, 0x1b, 0x1b, 0x01) // truncated
+
+	collect := func(opts ...ReadOption) ([]string, ReadStats) {
+		var events []string
+		opts = append(opts,
+			WithObisCallback(nil, func(le *ListEntry) { events = append(events, le.String()) }),
+			WithErrorCallback(func(err error) { events = append(events, "error: "+err.Error()) }),
+		)
+		stats, err := ReadWithStats(bufio.NewReader(bytes.NewReader(data)), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return events, stats
+	}
+
+	want, wantStats := collect()
+	for _, workers := range []int{2, 8} {
+		got, stats := collect(WithParseWorkers(workers))
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("%d workers: callbacks differ from sequential parsing", workers)
+		}
+		if stats != wantStats {
+			t.Errorf("%d workers: stats = %+v, want %+v", workers, stats, wantStats)
+		}
+	}
+
+	good := buildSMLFrame(smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 123456)))
+	bad := buildSMLFrame([]byte{0x72, 0x62, 0x01, 0x62, 0x01})
+	stream := bufio.NewReader(bytes.NewReader(bytes.Join([][]byte{good, bad, bad, good}, nil)))
+	if err := Read(stream, WithParseWorkers(4), WithMaxConsecutiveErrors(2)); !errors.Is(err, ErrTooManyErrors) {
+		t.Errorf("Read() = %v, want ErrTooManyErrors", err)
+	}
+	// the reader may be used once Read returned, the framing goroutine has ended
+	if err := Read(stream); err != nil {
+		t.Errorf("Read() of the rest = %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithScalerOverride
+// ---------------------------------------------------------------------------
+
+func TestRead_WithScalerOverride(t *testing.T) {
+	frame := buildSMLFrame(smlGetListResponse(
+		smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 2, 1234),
+		smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 50),
+	))
+
+	got := map[string]*ListEntry{}
+	err := Read(bufio.NewReader(bytes.NewReader(frame)),
+		WithScalerOverride(OctetString{1, 0, 1, 8}, 0),
+		WithScalerOverride(OctetString{1, 0, 1, 8, 0}, -1),
+		WithObisCallback(OctetString{}, func(le *ListEntry) { got[le.ObjectName()] = le }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	energy := got["1-0:1.8.0*255"]
+	if energy == nil || energy.Scaler() != 0.1 || energy.Float() != 123.4 {
+		t.Fatalf("energy entry %v should use the last matching override", energy)
+	}
+	if s := energy.ValueStringf("%.1f"); s != "123.4" {
+		t.Errorf("ValueStringf() = %q", s)
+	}
+	if power := got["1-0:16.7.0*255"]; power == nil || power.Float() != 50 {
+		t.Fatalf("power entry %v should keep its scaler", power)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: AnnotatedDump
+// ---------------------------------------------------------------------------
+
+func TestAnnotatedDump(t *testing.T) {
+	dump := AnnotatedDump(fixtureDZG)
+	for _, want := range []string{
+		"0000  1b 1b 1b 1b 01 01 01 01",
+		"begin sequence",
+		"tag: unsigned 257",
+		"serverId: octet string[10]",
+		"objName: octet string[6] 1-0:1.8.0*255",
+		"scaler: integer -1",
+		`value: octet string[3] "DZG"`,
+		"crc16: 955c (ok)",
+		"end sequence, padding 1, crc16 c3e1 (ok)",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump lacks %q:\n%s", want, dump)
+		}
+	}
+	if strings.Contains(dump, "parse error") {
+		t.Errorf("unexpected parse error:\n%s", dump)
+	}
+}
+
+func TestAnnotatedDump_MarksParseError(t *testing.T) {
+	payload := smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 1000))
+	// truncate within the value of the entry
+	dump := AnnotatedDump(payload[:len(payload)-10])
+
+	lines := strings.Split(strings.TrimSpace(dump), "\n")
+	if len(lines) < 3 {
+		t.Fatalf("dump too short:\n%s", dump)
+	}
+	if !strings.Contains(lines[len(lines)-3], "scaler: integer -1") {
+		t.Errorf("last annotated element should be the scaler:\n%s", dump)
+	}
+	if !strings.Contains(lines[len(lines)-2], "^ parse error: invalid length 4") {
+		t.Errorf("parse error not marked:\n%s", dump)
+	}
+	if !strings.HasSuffix(lines[len(lines)-1], "unparsed") {
+		t.Errorf("remaining bytes not dumped:\n%s", dump)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithSelectiveParsing
+// ---------------------------------------------------------------------------
+
+func TestRead_WithSelectiveParsing(t *testing.T) {
+	frame := buildSMLFrame(smlGetListResponse(
+		smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 1000),
+		smlListEntry([]byte{1, 0, 2, 8, 0, 255}, UNIT_WATT_HOUR, -1, 2000),
+		smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 50),
+		smlListEntry([]byte{1, 0, 36, 7, 0, 255}, UNIT_WATT, 0, 20),
+	))
+
+	for _, workers := range []int{1, 2} {
+		var got []string
+		var listLen int
+		err := Read(bufio.NewReader(bytes.NewReader(frame)), WithSelectiveParsing(), WithParseWorkers(workers),
+			WithObisCallback(OctetString{1, 0, 1, 8}, func(le *ListEntry) { got = append(got, le.ObjectName()) }),
+			WithObisPatternCallback(ObisPattern{1, 0, OBIS_WILDCARD, 7, 0}, func(le *ListEntry) {
+				got = append(got, le.ObjectName())
+			}),
+			WithGetListResponseCallback(func(msg GetListResponse) { listLen = len(msg.ValList) }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"1-0:1.8.0*255", "1-0:16.7.0*255", "1-0:36.7.0*255"}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("workers=%d: got %v, want %v", workers, got, want)
+		}
+		if listLen != 3 {
+			t.Errorf("workers=%d: GetListResponse has %d entries, want 3", workers, listLen)
+		}
+	}
+}
+
+func TestRead_WithSelectiveParsing_Fallback(t *testing.T) {
+	var fallback int
+	err := Read(bufio.NewReader(bytes.NewReader(fixtureDZG)), WithSelectiveParsing(),
+		WithObisCallback(OctetString{1, 0, 1, 8, 0}, func(*ListEntry) {}),
+		WithObisFallback(func(*ListEntry) { fallback++ }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fallback != 4 {
+		t.Errorf("fallback called %d times, want 4", fallback)
+	}
+}
+
+func TestListParse_SkipsFilteredEntries(t *testing.T) {
+	entries := [][]byte{
+		smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 1000),
+		// compound value and status of the skipped entry must be skipped as a whole
+		{0x77, 0x07, 1, 0, 2, 8, 0, 255, 0x62, 0x08, 0x01, 0x62, UNIT_WATT_HOUR, 0x52, 0xff,
+			0x72, 0x62, 0x01, 0x52, 0x03, 0x01},
+		smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 50),
+	}
+	data := []byte{0x73}
+	for _, e := range entries {
+		data = append(data, e...)
+	}
+
+	buf := &Buffer{Bytes: data, parseConfig: parseConfig{entryFilter: func(objName OctetString) bool { return objName[2] != 2 }}}
+	list, err := ListParse(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ObjectName() != "1-0:1.8.0*255" || list[1].ObjectName() != "1-0:16.7.0*255" {
+		t.Fatalf("got %v", list)
+	}
+	if buf.Cursor != len(data) {
+		t.Errorf("cursor at %d, want %d", buf.Cursor, len(data))
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: choice-encoded objName
+// ---------------------------------------------------------------------------
+
+func TestListEntryParse_ChoiceObjName(t *testing.T) {
+	// objName wrapped in a choice with tag 2, followed by a regular entry
+	choice := []byte{0x77, 0x72, 0x62, 0x02, 0x07, 1, 0, 1, 8, 0, 255,
+		0x01, 0x01, 0x62, UNIT_WATT_HOUR, 0x52, 0xff, 0x63, 0x03, 0xe8, 0x01}
+	frame := buildSMLFrame(smlGetListResponse(choice,
+		smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 50)))
+
+	for _, selective := range []bool{false, true} {
+		got := map[string]float64{}
+		opts := []ReadOption{WithObisCallback(OctetString{1, 0}, func(le *ListEntry) { got[le.ObjectName()] = le.Float() })}
+		if selective {
+			opts = append(opts, WithSelectiveParsing())
+		}
+		if err := Read(bufio.NewReader(bytes.NewReader(frame)), opts...); err != nil {
+			t.Fatal(err)
+		}
+		if got["1-0:1.8.0*255"] != 100 || got["1-0:16.7.0*255"] != 50 || len(got) != 2 {
+			t.Errorf("selective=%t: got %v", selective, got)
+		}
+	}
+}
+
+func TestListEntryParse_ChoiceObjNameInvalid(t *testing.T) {
+	// choice of 3 elements
+	buf := &Buffer{Bytes: []byte{0x77, 0x73, 0x62, 0x02, 0x02, 0x01, 0x01}}
+	if _, err := ListEntryParse(buf); err == nil || !strings.Contains(err.Error(), "objName: choice") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithMaxListEntries, WithMaxValueLen
+// ---------------------------------------------------------------------------
+
+func TestRead_WithMaxListEntries(t *testing.T) {
+	entry := func(c byte) []byte { return smlListEntry([]byte{1, 0, c, 8, 0, 255}, UNIT_WATT_HOUR, -1, 1000) }
+	data := append(buildSMLFrame(smlGetListResponse(entry(1), entry(2), entry(3))),
+		buildSMLFrame(smlGetListResponse(entry(1), entry(2)))...)
+
+	var calls int
+	var errs []error
+	err := Read(bufio.NewReader(bytes.NewReader(data)), WithMaxListEntries(2),
+		WithErrorCallback(func(err error) { errs = append(errs, err) }),
+		WithObisCallback(OctetString{}, func(*ListEntry) { calls++ }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("callback called %d times, want 2 for the second file only", calls)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrLimitExceeded) {
+		t.Fatalf("errors %v", errs)
+	}
+	if !strings.Contains(errs[0].Error(), "3 entries (max 2)") {
+		t.Errorf("error %q", errs[0])
+	}
+}
+
+func TestRead_WithMaxValueLen(t *testing.T) {
+	long := []byte{0x77, 0x07, 1, 0, 96, 1, 0, 255, 0x01, 0x01, 0x01, 0x01, 0x0b, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 0x01}
+	short := smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 1000)
+
+	for _, tc := range []struct {
+		max  int
+		want int
+		errs int
+	}{
+		{0, 2, 0},
+		{10, 2, 0},
+		{9, 0, 1},
+	} {
+		var calls int
+		var errs []error
+		err := ParseBytes(buildSMLFrame(smlGetListResponse(short, long)), WithMaxValueLen(tc.max),
+			WithErrorCallback(func(err error) { errs = append(errs, err) }),
+			WithObisCallback(OctetString{}, func(*ListEntry) { calls++ }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls != tc.want || len(errs) != tc.errs {
+			t.Errorf("max %d: %d calls, errors %v", tc.max, calls, errs)
+		}
+		for _, err := range errs {
+			if !errors.Is(err, ErrLimitExceeded) {
+				t.Errorf("max %d: error %v", tc.max, err)
+			}
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ListEntry.LocalizedValueString()
+// ---------------------------------------------------------------------------
+
+func TestLocalizedValueString(t *testing.T) {
+	for _, tc := range []struct {
+		unit    uint8
+		scaler  int8
+		value   int64
+		german  string
+		english string
+	}{
+		{UNIT_WATT_HOUR, 2, 12345, "1.234,5 kWh", "1,234.5 kWh"},
+		{UNIT_WATT_HOUR, 2, 123455, "12.345,5 kWh", "12,345.5 kWh"},
+		{UNIT_WATT_HOUR, 2, 1234, "123,4 kWh", "123.4 kWh"},
+		{UNIT_WATT_HOUR, -1, 123456789, "12.345,6789 kWh", "12,345.6789 kWh"},
+		{UNIT_WATT_HOUR, 4, 123, "1.230 kWh", "1,230 kWh"},
+		{UNIT_WATT, 0, -1523, "-1.523 W", "-1,523 W"},
+		{UNIT_VOLT, -1, 2304, "230,4 V", "230.4 V"},
+		{0, 0, 1234567, "1.234.567", "1,234,567"},
+	} {
+		le := &ListEntry{
+			Unit:   tc.unit,
+			scaler: tc.scaler,
+			Value:  Value{Typ: OCTET_TYPE_INTEGER | TYPE_NUMBER_32, DataInt: tc.value},
+		}
+		if got := le.LocalizedValueString(DECIMAL_STYLE_GERMAN); got != tc.german {
+			t.Errorf("German: got %q, want %q", got, tc.german)
+		}
+		if got := le.LocalizedValueString(DECIMAL_STYLE_ENGLISH); got != tc.english {
+			t.Errorf("English: got %q, want %q", got, tc.english)
+		}
+	}
+
+	le := &ListEntry{Value: Value{Typ: OCTET_TYPE_OCTET_STRING, DataBytes: OctetString("DZG")}}
+	if got := le.LocalizedValueString(DECIMAL_STYLE_GERMAN); got != le.ValueString() {
+		t.Errorf("octet string: got %q", got)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ParseFrames
+// ---------------------------------------------------------------------------
+
+// drainFrames collects the messages and errors of ParseFrames until both channels are closed
+func drainFrames(messages <-chan *Message, errs <-chan error) ([]*Message, []error) {
+	var gotMsgs []*Message
+	var gotErrs []error
+	for messages != nil || errs != nil {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				messages = nil
+				continue
+			}
+			gotMsgs = append(gotMsgs, msg)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			gotErrs = append(gotErrs, err)
+		}
+	}
+	return gotMsgs, gotErrs
+}
+
+func TestParseFrames(t *testing.T) {
+	list := smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 1000))
+	frame := buildSMLFrame(list)
+
+	frames := make(chan []byte, 5)
+	frames <- fixtureDZG
+	frames <- list                      // payload without begin and end sequences
+	frames <- frame[:len(frame)-4]      // truncated
+	frames <- append(frame, 0xaa, 0xbb) // trailing bytes
+	frames <- frame
+	close(frames)
+
+	var callbacks int
+	msgs, errs := drainFrames(ParseFrames(frames,
+		WithObisCallback(OctetString{1, 0, 1, 8, 0}, func(*ListEntry) { callbacks++ })))
+
+	var tags []uint32
+	for _, msg := range msgs {
+		tags = append(tags, msg.MessageBody.Tag)
+	}
+	want := []uint32{MESSAGE_OPEN_RESPONSE, MESSAGE_GET_LIST_RESPONSE, MESSAGE_CLOSE_RESPONSE,
+		MESSAGE_GET_LIST_RESPONSE, MESSAGE_GET_LIST_RESPONSE}
+	if fmt.Sprint(tags) != fmt.Sprint(want) {
+		t.Errorf("tags %x, want %x", tags, want)
+	}
+	if callbacks != 3 {
+		t.Errorf("callback called %d times, want 3", callbacks)
+	}
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "end sequence missing") ||
+		!strings.Contains(errs[1].Error(), "2 bytes after end sequence") {
+		t.Errorf("errors %v", errs)
+	}
+}
+
+func TestParseFrames_Filters(t *testing.T) {
+	frames := make(chan []byte, 1)
+	frames <- fixtureDZG
+	close(frames)
+
+	msgs, errs := drainFrames(ParseFrames(frames, WithServerIDFilter(OctetString{0xff})))
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	for _, msg := range msgs {
+		if msg.MessageBody.Tag == MESSAGE_GET_LIST_RESPONSE {
+			t.Errorf("GetListResponse of other server id emitted")
+		}
+	}
+	if len(msgs) != 2 {
+		t.Errorf("%d messages, want open and close response", len(msgs))
+	}
+}
+
+func TestParseFrames_TooManyErrors(t *testing.T) {
+	frames := make(chan []byte, 3)
+	frames <- []byte{0x76, 0x01}
+	frames <- []byte{0x76, 0x02}
+	frames <- fixtureDZG
+	close(frames)
+
+	var reported int
+	msgs, errs := drainFrames(ParseFrames(frames, WithMaxConsecutiveErrors(2),
+		WithErrorCallback(func(error) { reported++ })))
+	if len(msgs) != 0 {
+		t.Errorf("%d messages emitted after the error budget was exhausted", len(msgs))
+	}
+	if len(errs) != 3 || !errors.Is(errs[2], ErrTooManyErrors) {
+		t.Errorf("errors %v", errs)
+	}
+	if reported != 2 {
+		t.Errorf("error callback called %d times, want 2", reported)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: files without OpenResponse and CloseResponse
+// ---------------------------------------------------------------------------
+
+func TestRead_BareGetListResponse(t *testing.T) {
+	// a single GetListResponse without the surrounding OpenResponse and CloseResponse
+	frame := buildSMLFrame(smlGetListResponse(
+		smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 1000),
+		smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 50),
+	))
+
+	for _, workers := range []int{1, 2} {
+		var entries, all, lists, envelope int
+		stats, err := ReadWithStats(bufio.NewReader(bytes.NewReader(frame)), WithParseWorkers(workers),
+			WithObisCallback(OctetString{1, 0}, func(*ListEntry) { entries++ }),
+			WithObisCallbackAll(OctetString{1, 0, 1, 8, 0}, func(e []*ListEntry) { all += len(e) }),
+			WithGetListResponseCallback(func(GetListResponse) { lists++ }),
+			WithOpenResponseCallback(func(OpenResponse) { envelope++ }),
+			WithCloseResponseCallback(func(CloseResponse) { envelope++ }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entries != 2 || all != 1 || lists != 1 || envelope != 0 {
+			t.Errorf("workers=%d: %d entries, %d via callback all, %d lists, %d envelope messages",
+				workers, entries, all, lists, envelope)
+		}
+		if stats.FramesParsed != 1 || stats.FramesSkipped != 0 {
+			t.Errorf("workers=%d: stats %+v", workers, stats)
+		}
+	}
+
+	readings, err := ReadFrame(bufio.NewReader(bytes.NewReader(frame)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readings) != 2 || readings[0].Obis != "1-0:1.8.0*255" || readings[0].Value != 100 {
+		t.Errorf("ReadFrame() = %v", readings)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: NetEnergy
+// ---------------------------------------------------------------------------
+
+func TestNetEnergy(t *testing.T) {
+	entry := func(obis []byte, unit uint8, scaler int8, value uint32) *ListEntry {
+		le, err := ListEntryParse(&Buffer{Bytes: smlListEntry(obis, unit, scaler, value)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return le
+	}
+	imp := entry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 12345)
+	imp181 := entry([]byte{1, 0, 1, 8, 1, 255}, UNIT_WATT_HOUR, -1, 99999)
+	exp := entry([]byte{1, 0, 2, 8, 0, 255}, UNIT_WATT_HOUR, 0, 500)
+	expVarh := entry([]byte{1, 0, 2, 8, 0, 255}, UNIT_VAR_HOUR, 0, 500)
+
+	for _, tc := range []struct {
+		name    string
+		entries []*ListEntry
+		want    float64
+		ok      bool
+	}{
+		{"import and export", []*ListEntry{imp181, exp, imp}, 734.5, true},
+		{"export exceeds import", []*ListEntry{entry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, 100), exp}, -400, true},
+		{"export missing", []*ListEntry{imp, imp181}, 0, false},
+		{"import missing", []*ListEntry{exp}, 0, false},
+		{"units differ", []*ListEntry{imp, expVarh}, 0, false},
+	} {
+		net, ok := NetEnergy(&GetListResponse{ValList: tc.entries})
+		if ok != tc.ok || math.Abs(net-tc.want) > 1e-9 {
+			t.Errorf("%s: NetEnergy() = %v, %v, want %v, %v", tc.name, net, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithTrace
+// ---------------------------------------------------------------------------
+
+func TestWithTrace(t *testing.T) {
+	payload := smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 1000))
+
+	var trace bytes.Buffer
+	if err := ParsePayload(payload, WithTrace(&trace)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"MessageParse                       offset    0  tl 76  list\n",
+		"GetListResponseParse               offset   13  tl 77  list\n",
+		"ListEntryParse                     offset   21  tl 77  list\n",
+		"(*Buffer).StatusRawParse           offset   29  tl 01  skipped\n",
+		"(*Buffer).NumberParse              offset   33  tl 52  integer\n",
+	} {
+		if !strings.Contains(trace.String(), want) {
+			t.Errorf("trace lacks %q:\n%s", want, trace.String())
+		}
+	}
+
+	// the last step shows where parsing failed
+	trace.Reset()
+	var errs []error
+	ParsePayload(payload[:36], WithTrace(&trace), WithErrorCallback(func(err error) { errs = append(errs, err) }))
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	if len(errs) != 1 || !strings.HasPrefix(lines[len(lines)-1], "(*Buffer).NumberParse              offset   35  tl 65") {
+		t.Errorf("errors %v, trace:\n%s", errs, trace.String())
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: MessageTags
+// ---------------------------------------------------------------------------
+
+func TestMessageTags(t *testing.T) {
+	tags, err := MessageTags(fixtureDZG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(tags); got != "[OpenResponse GetListResponse CloseResponse]" {
+		t.Errorf("MessageTags() = %s", got)
+	}
+
+	// payload with two lists and a vendor specific message
+	payload := append(smlGetListResponse(), smlGetListResponse()...)
+	payload = append(payload, smlMessage(0x0000ff99, []byte{0x72, 0x62, 0x01, 0x01})...)
+	if tags, err = MessageTags(payload); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(tags); got != "[GetListResponse GetListResponse 0x0000ff99]" {
+		t.Errorf("MessageTags() = %s", got)
+	}
+
+	tags, err = MessageTags(payload[:len(payload)-3])
+	if err == nil || !strings.HasPrefix(err.Error(), "message 2:") || len(tags) != 2 {
+		t.Errorf("truncated payload: %v, %v", tags, err)
+	}
+}
+
+func TestMessageTag_String(t *testing.T) {
+	if s := MessageTag(MESSAGE_ATTENTION_RESPONSE).String(); s != "AttentionResponse" {
+		t.Errorf("String() = %q", s)
+	}
+	if s := MessageTag(0x1234).String(); s != "0x00001234" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithCrcWarn
+// ---------------------------------------------------------------------------
+
+func TestWithCrcWarn(t *testing.T) {
+	payload := smlGetListResponse(smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, 1000))
+	// flip a bit of the value, invalidating the message's checksum
+	i := bytes.Index(payload, []byte{0x00, 0x00, 0x03, 0xe8})
+	payload[i+3] ^= 0x01
+
+	if _, err := ParseMessages(payload); !errors.Is(err, ErrCrcMismatch) {
+		t.Fatalf("ParseMessages() error %v", err)
+	}
+
+	var values []float64
+	var errs []error
+	callback := WithObisCallback(OctetString{1, 0, 1, 8, 0}, func(le *ListEntry) { values = append(values, le.Float()) })
+	errorCallback := WithErrorCallback(func(err error) { errs = append(errs, err) })
+
+	if err := ParseBytes(buildSMLFrame(payload), callback, errorCallback); err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 0 || len(errs) != 1 || !errors.Is(errs[0], ErrCrcMismatch) {
+		t.Fatalf("without WithCrcWarn: values %v, errors %v", values, errs)
+	}
+
+	errs = nil
+	frames := make(chan []byte, 1)
+	frames <- buildSMLFrame(payload)
+	close(frames)
+	msgs, _ := drainFrames(ParseFrames(frames, WithCrcWarn(), callback, errorCallback))
+	if len(values) != 1 || values[0] != 1001 {
+		t.Errorf("with WithCrcWarn: values %v", values)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrCrcMismatch) || !strings.Contains(errs[0].Error(), "unverified") {
+		t.Errorf("with WithCrcWarn: errors %v", errs)
+	}
+	if len(msgs) != 1 || !msgs[0].CrcUnverified {
+		t.Errorf("message not marked unverified: %+v", msgs)
+	}
+
+	frames = make(chan []byte, 1)
+	frames <- fixtureDZG
+	close(frames)
+	msgs, _ = drainFrames(ParseFrames(frames, WithCrcWarn()))
+	for _, msg := range msgs {
+		if msg.CrcUnverified {
+			t.Errorf("valid message %x marked unverified", msg.MessageBody.Tag)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: GetListResponse.GroupByValTime
+// ---------------------------------------------------------------------------
+
+func TestGetListResponse_GroupByValTime(t *testing.T) {
+	// entry with valTime as timestamp
+	stamped := func(c byte, ts uint32) []byte {
+		return []byte{0x77, 0x07, 1, 0, c, 8, 0, 255, 0x01,
+			0x72, 0x62, 0x02, 0x65, byte(ts >> 24), byte(ts >> 16), byte(ts >> 8), byte(ts),
+			0x62, UNIT_WATT_HOUR, 0x52, 0x00, 0x62, c, 0x01}
+	}
+	data := []byte{0x77, 0x01, 0x01, 0x01, 0x72, 0x62, 0x02, 0x65, 0x00, 0x00, 0x00, 0x64, 0x74}
+	data = append(data, stamped(1, 50)...)
+	data = append(data, smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 7)...)
+	data = append(data, stamped(2, 60)...)
+	data = append(data, stamped(3, 50)...)
+	data = append(data, 0x01, 0x01)
+
+	list, err := GetListResponseParse(&Buffer{Bytes: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := list.GroupByValTime()
+	names := func(entries []*ListEntry) string {
+		var s []string
+		for _, le := range entries {
+			s = append(s, le.ObjectName())
+		}
+		return strings.Join(s, " ")
+	}
+	if len(groups) != 3 {
+		t.Fatalf("got %d groups, want 3", len(groups))
+	}
+	for key, want := range map[Time]string{
+		50:  "1-0:1.8.0*255 1-0:3.8.0*255",
+		60:  "1-0:2.8.0*255",
+		100: "1-0:16.7.0*255",
+	} {
+		if got := names(groups[key]); got != want {
+			t.Errorf("group %d: %s, want %s", key, got, want)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Message.String
+// ---------------------------------------------------------------------------
+
+func TestMessage_String(t *testing.T) {
+	// transactionId ab cd, groupNo 0, abortOnError 0, CloseResponse without signature
+	payload := []byte{0x76, 0x03, 0xab, 0xcd, 0x62, 0x00, 0x62, 0x00,
+		0x72, 0x63, 0x02, 0x01, 0x71, 0x01, 0x63, 0x12, 0x34, 0x00}
+	msg, err := MessageParse(&Buffer{Bytes: payload})
+	if err != nil {
+		t.Fatalf("MessageParse() error %v", err)
+	}
+	if !bytes.Equal(msg.TransactionID, []byte{0xab, 0xcd}) {
+		t.Errorf("TransactionID = % x", msg.TransactionID)
+	}
+	want := "CloseResponse transactionId abcd group 0 crc 1234"
+	if s := msg.String(); s != want {
+		t.Errorf("String() = %q, want %q", s, want)
+	}
+}
+
+func TestMessageParse_GroupNoAbortOnError(t *testing.T) {
+	// groupNo 3, abortOnError 2, followed by a CloseResponse and a second message
+	first := []byte{0x76, 0x02, 0x01, 0x62, 0x03, 0x62, 0x02,
+		0x72, 0x63, 0x02, 0x01, 0x71, 0x01}
+	crc := crc16Calculate(first, len(first))
+	first = append(first, 0x63, byte(crc>>8), byte(crc), 0x00)
+	payload := append(first, smlMessage(MESSAGE_CLOSE_RESPONSE, []byte{0x71, 0x01})...)
+
+	buf := &Buffer{Bytes: payload}
+	msg, err := MessageParse(buf, true)
+	if err != nil {
+		t.Fatalf("MessageParse() error %v", err)
+	}
+	if msg.GroupNo() != 3 || msg.AbortOnError != 2 {
+		t.Errorf("groupNo %d, abortOnError %d", msg.GroupNo(), msg.AbortOnError)
+	}
+	if msg.MessageBody.Tag != MESSAGE_CLOSE_RESPONSE {
+		t.Errorf("Tag = %x", msg.MessageBody.Tag)
+	}
+	if buf.Cursor != len(first) {
+		t.Fatalf("Cursor = %d, want %d", buf.Cursor, len(first))
+	}
+	if msg, err = MessageParse(buf, true); err != nil || msg.GroupNo() != 0 {
+		t.Errorf("second message: %v, %v", msg, err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ReadAverage
+// ---------------------------------------------------------------------------
+
+func TestReadAverage(t *testing.T) {
+	power := []byte{1, 0, 16, 7, 0, 255}
+	energy := []byte{1, 0, 1, 8, 0, 255}
+	var data []byte
+	for _, frame := range [][]byte{
+		buildSMLFrame(smlGetListResponse(smlListEntry(power, UNIT_WATT, 0, 400), smlListEntry(energy, UNIT_WATT_HOUR, 0, 1000))),
+		buildSMLFrame(smlGetListResponse(smlListEntry(power, UNIT_WATT, 0, 500))),
+		{0x1b, 0x1b, 0x1b, 0x1b, 0x01, 0x01, 0x01, 0x01, 0x1b, 0x1b, 0x1b, 0x1b, 0x1a, 0x00, 0x00, 0x00}, // unparsable
+		buildSMLFrame(smlGetListResponse(smlListEntry(power, UNIT_WATT, 0, 600), smlListEntry(energy, UNIT_WATT_HOUR, 0, 1010))),
+		buildSMLFrame(smlGetListResponse(smlListEntry(power, UNIT_WATT, 0, 9999))),
+	} {
+		data = append(data, frame...)
+	}
+	codes := []OctetString{power, energy, {1, 0, 2, 8, 0, 255}}
+
+	means, err := ReadAverage(bufio.NewReader(bytes.NewReader(data)), 3, codes)
+	if err != nil {
+		t.Fatalf("ReadAverage() error %v", err)
+	}
+	want := map[string]float64{"1-0:16.7.0*255": 500, "1-0:1.8.0*255": 1005}
+	if len(means) != len(want) || means["1-0:16.7.0*255"] != 500 || means["1-0:1.8.0*255"] != 1005 {
+		t.Errorf("ReadAverage() = %v, want %v", means, want)
+	}
+
+	if _, err := ReadAverage(bufio.NewReader(bytes.NewReader(data)), 5, codes); !errors.Is(err, io.EOF) {
+		t.Errorf("ReadAverage() of too few frames error %v, want io.EOF", err)
+	}
+	if _, err := ReadAverage(bufio.NewReader(bytes.NewReader(data)), 0, codes); err == nil {
+		t.Error("expected error for 0 frames")
+	}
+	if _, err := ReadAverage(bufio.NewReader(bytes.NewReader(data)), 1, nil); err == nil {
+		t.Error("expected error for no OBIS codes")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: ListEntry.SignedData
+// ---------------------------------------------------------------------------
+
+func TestListEntry_SignedData(t *testing.T) {
+	entry := smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 123456)
+	// replace the skipped signature by a 2 byte one
+	signed := append(append([]byte{}, entry[:len(entry)-1]...), 0x03, 0xaa, 0xbb)
+
+	le, err := ListEntryParse(&Buffer{Bytes: signed})
+	if err != nil {
+		t.Fatalf("ListEntryParse() error %v", err)
+	}
+	// everything but the list's type-length field and the signature
+	if want := entry[1 : len(entry)-1]; !bytes.Equal(le.SignedData(), want) {
+		t.Errorf("SignedData() = % x, want % x", le.SignedData(), want)
+	}
+
+	if le, err = ListEntryParse(&Buffer{Bytes: entry}); err != nil || le.SignedData() != nil {
+		t.Errorf("SignedData() of unsigned entry = % x, %v", le.SignedData(), err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithListName
+// ---------------------------------------------------------------------------
+
+func TestRead_WithListName(t *testing.T) {
+	// a list of current values and a historical one, both containing 1.8.0
+	named := func(name []byte, value uint32) []byte {
+		data := []byte{0x77, 0x01, 0x03, 0x01, 0x02, byte(len(name) + 1)}
+		data = append(data, name...)
+		data = append(data, 0x01, 0x71)
+		data = append(data, smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, value)...)
+		data = append(data, 0x01, 0x01)
+		return smlMessage(MESSAGE_GET_LIST_RESPONSE, data)
+	}
+	frame := buildSMLFrame(append(named([]byte{1, 0, 99, 1, 0, 255}, 2000), named([]byte{0, 0, 98, 1, 1, 255}, 1000)...))
+
+	read := func(opts ...ReadOption) []float64 {
+		var values []float64
+		opts = append(opts, WithObisCallback(OctetString{1, 0, 1, 8, 0}, func(le *ListEntry) {
+			values = append(values, le.Float())
+		}))
+		if err := Read(bufio.NewReader(bytes.NewReader(frame)), opts...); err != nil {
+			t.Fatalf("Read() error %v", err)
+		}
+		return values
+	}
+
+	if values := read(); len(values) != 2 {
+		t.Fatalf("without WithListName got %v, want both lists", values)
+	}
+	if values := read(WithListName(OctetString{1, 0, 99, 1, 0})); len(values) != 1 || values[0] != 2000 {
+		t.Errorf("WithListName(current) got %v, want [2000]", values)
+	}
+	if values := read(WithListName(OctetString{0, 0, 98, 1})); len(values) != 1 || values[0] != 1000 {
+		t.Errorf("WithListName(historical) got %v, want [1000]", values)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: FreezeDetector
+// ---------------------------------------------------------------------------
+
+func TestFreezeDetector(t *testing.T) {
+	entry := func(value uint32) *ListEntry {
+		le, err := ListEntryParse(&Buffer{Bytes: smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, value)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return le
+	}
+	var events []FreezeEvent
+	fd := NewFreezeDetector(3, time.Minute, func(ev FreezeEvent) { events = append(events, ev) })
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, value := range []uint32{100, 100, 100, 100, 100, 100, 101, 101} {
+		fd.Observe(entry(value), start.Add(time.Duration(i)*30*time.Second))
+	}
+	// 3 unchanged readings after 90s, reported once; 101 doesn't freeze within 30s
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1: %v", len(events), events)
+	}
+	if ev := events[0]; ev.Obis != "1-0:1.8.0*255" || ev.Value != 100 || !ev.Since.Equal(start) || ev.Frames != 3 {
+		t.Errorf("event = %+v", ev)
+	}
+
+	// readings keep coming, but too fast for the duration
+	events = nil
+	fd = NewFreezeDetector(2, time.Hour, func(ev FreezeEvent) { events = append(events, ev) })
+	for i := 0; i < 10; i++ {
+		fd.Observe(entry(100), start.Add(time.Duration(i)*time.Second))
+	}
+	if len(events) != 0 {
+		t.Errorf("got events %v before duration elapsed", events)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: WithRegisterDecoder
+// ---------------------------------------------------------------------------
+
+type testVendorStatus struct {
+	Code  byte
+	Alarm bool
+}
+
+func (s testVendorStatus) String() string {
+	return fmt.Sprintf("code %d alarm %v", s.Code, s.Alarm)
+}
+
+func TestRead_WithRegisterDecoder(t *testing.T) {
+	vendor := []byte{0x77, 0x07, 1, 0, 96, 50, 1, 1, 0x01, 0x01, 0x01, 0x01, 0x03, 0x2a, 0x80, 0x01}
+	frame := buildSMLFrame(smlGetListResponse(vendor, smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, 0, 1000)))
+	dec := RegisterDecoderFunc(func(value Value) (interface{}, error) {
+		if len(value.DataBytes) != 2 {
+			return nil, fmt.Errorf("invalid length %d", len(value.DataBytes))
+		}
+		return testVendorStatus{Code: value.DataBytes[0], Alarm: value.DataBytes[1]&0x80 != 0}, nil
+	})
+
+	entries := map[string]*ListEntry{}
+	err := Read(bufio.NewReader(bytes.NewReader(frame)),
+		WithRegisterDecoder(OctetString{1, 0, 96, 50}, dec),
+		WithObisCallback(OctetString{}, func(le *ListEntry) { entries[le.ObjectName()] = le }))
+	if err != nil {
+		t.Fatalf("Read() error %v", err)
+	}
+
+	le := entries["1-0:96.50.1*1"]
+	v, err := le.ValueDecoded()
+	if err != nil || v != (testVendorStatus{Code: 42, Alarm: true}) {
+		t.Errorf("ValueDecoded() = %v, %v", v, err)
+	}
+	if s := le.ValueString(); s != "code 42 alarm true" {
+		t.Errorf("ValueString() = %q", s)
+	}
+
+	energy := entries["1-0:1.8.0*255"]
+	if _, err := energy.ValueDecoded(); !errors.Is(err, ErrNoDecoder) {
+		t.Errorf("ValueDecoded() without decoder error %v", err)
+	}
+	if s := energy.ValueStringf("%.0f"); s != "1000" {
+		t.Errorf("ValueStringf() = %q", s)
+	}
+
+	// values failing to decode are rendered as usual
+	le.Value.DataBytes = []byte{0x2a}
+	if _, err := le.ValueDecoded(); err == nil {
+		t.Error("expected decode error")
+	}
+	if s := le.ValueString(); s != "2a" {
+		t.Errorf("ValueString() of undecodable value = %q", s)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: History
+// ---------------------------------------------------------------------------
+
+func TestHistory(t *testing.T) {
+	// profile periods newest first, followed by the current values at 1600002700
+	payload := append(smlProfileListResponse(1600001800, 1251, 0), smlProfileListResponse(1600000900, 1240, 0)...)
+	payload = append(payload, smlProfileListResponse(1600000000, 1234, 0)...)
+	// the newest period again, e.g. from a meter repeating it until the next period ends
+	payload = append(payload, smlProfileListResponse(1600001800, 1251, 0)...)
+	list := []byte{0x77, 0x01, 0x03, 0x01, 0x02, 0x01, 0x72, 0x62, 0x02, 0x65, 0x5f, 0x5e, 0x1a, 0x8c, 0x72}
+	list = append(list, smlListEntry([]byte{1, 0, 1, 8, 0, 255}, UNIT_WATT_HOUR, -1, 1260)...)
+	list = append(list, smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 300)...)
+	payload = append(payload, smlMessage(MESSAGE_GET_LIST_RESPONSE, append(list, 0x01, 0x01))...)
+
+	h := NewHistory(2)
+	err := Read(bufio.NewReader(bytes.NewReader(buildSMLFrame(payload))),
+		WithGetListResponseCallback(h.AddList), WithGetProfileListResponseCallback(h.AddProfile))
+	if err != nil {
+		t.Fatalf("Read() error %v", err)
+	}
+
+	// the oldest profile point is dropped by the limit
+	series := h.Series("1-0:1.8.0*255")
+	want := TimeSeries{{1600000900, 124.0}, {1600001800, 125.1}, {1600002700, 126.0}}
+	if len(series) != len(want) {
+		t.Fatalf("Series() = %v, want %v", series, want)
+	}
+	for i := range want {
+		if series[i].Time != want[i].Time || math.Abs(series[i].Value-want[i].Value) > 1e-9 {
+			t.Errorf("point %d = %v, want %v", i, series[i], want[i])
+		}
+	}
+
+	if series := h.Series("1-0:16.7.0*255"); len(series) != 1 || series[0].Value != 300 {
+		t.Errorf("Series() of current only register = %v", series)
+	}
+	if series := h.Series("1-0:2.8.0*255"); len(series) != 2 || series[1].Value != 0 {
+		t.Errorf("Series() of profile only register = %v", series)
+	}
+	if series := h.Series("1-0:3.8.0*255"); len(series) != 0 {
+		t.Errorf("Series() of unknown register = %v", series)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: Unknown time choices
+// ---------------------------------------------------------------------------
+
+func TestRead_UnknownTimeChoice(t *testing.T) {
+	for _, tag := range []byte{0x09, 0x00} {
+		// valTime with an unknown choice tag around a list, followed by a regular entry
+		bogus := []byte{0x77, 0x07, 1, 0, 1, 8, 0, 255, 0x01,
+			0x72, 0x62, tag, 0x72, 0x65, 0x00, 0x00, 0x00, 0x01, 0x52, 0x00,
+			0x62, UNIT_WATT_HOUR, 0x52, 0x00, 0x62, 0x2a, 0x01}
+		frame := buildSMLFrame(smlGetListResponse(bogus, smlListEntry([]byte{1, 0, 16, 7, 0, 255}, UNIT_WATT, 0, 300)))
+
+		var list GetListResponse
+		var entries []*ListEntry
+		var errs []error
+		read := func(opts ...ReadOption) {
+			entries, errs = nil, nil
+			opts = append(opts,
+				WithGetListResponseCallback(func(msg GetListResponse) { list = msg }),
+				WithObisCallback(OctetString{}, func(le *ListEntry) { entries = append(entries, le) }),
+				WithErrorCallback(func(err error) { errs = append(errs, err) }))
+			if err := Read(bufio.NewReader(bytes.NewReader(frame)), opts...); err != nil {
+				t.Fatalf("tag %d: Read() error %v", tag, err)
+			}
+		}
+
+		read()
+		if len(entries) != 2 || len(errs) != 0 {
+			t.Fatalf("tag %d: got %d entries, errors %v", tag, len(entries), errs)
+		}
+		if wall, kind := entries[0].ValTime(); kind != TIME_KIND_UNKNOWN || !wall.IsZero() || entries[0].Float() != 42 {
+			t.Errorf("tag %d: ValTime() = %v, %d, value %v", tag, wall, kind, entries[0].Float())
+		}
+		if entries[1].Float() != 300 {
+			t.Errorf("tag %d: entry after unknown time = %v", tag, entries[1].Float())
+		}
+		if groups := list.GroupByValTime(); len(groups) != 1 || len(groups[list.ActSensorTime]) != 2 {
+			t.Errorf("tag %d: GroupByValTime() = %v, want all entries under ActSensorTime", tag, groups)
+		}
+
+		read(WithSanityCheck())
+		if len(entries) != 1 || len(errs) != 1 || !errors.Is(errs[0], ErrUnknownTimeKind) {
+			t.Errorf("tag %d: with sanity check got %d entries, errors %v", tag, len(entries), errs)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests: MonotonicValidator
+// ---------------------------------------------------------------------------
+
+func TestMonotonicValidator(t *testing.T) {
+	entry := func(c byte, value uint32) *ListEntry {
+		le, err := ListEntryParse(&Buffer{Bytes: smlListEntry([]byte{1, 0, c, 8, 0, 255}, UNIT_WATT_HOUR, 0, value)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return le
+	}
+	var suspects []SuspectReading
+	mv := NewMonotonicValidator(100, func(s SuspectReading) { suspects = append(suspects, s) })
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		le        *ListEntry
+		plausible bool
+	}{
+		{entry(1, 0xfffffff0), true},
+		{entry(1, 0xfffffff0), true}, // unchanged
+		{entry(1, 0x10), true},       // rollover by 32
+		{entry(1, 0x20), true},
+		{entry(2, 5), true}, // other register
+		{entry(1, 0x08), false},
+		{entry(1, 0x09), true}, // the suspect value is the new reference
+	} {
+		if plausible := mv.Observe(tc.le, now); plausible != tc.plausible {
+			t.Errorf("Observe(%s %v) = %v, want %v", tc.le.ObjectName(), tc.le.Float(), plausible, tc.plausible)
+		}
+	}
+	if len(suspects) != 1 {
+		t.Fatalf("got suspects %v, want 1", suspects)
+	}
+	if s := suspects[0]; s.Obis != "1-0:1.8.0*255" || s.Prev != 0x20 || s.Cur != 0x08 || !s.Time.Equal(now) {
+		t.Errorf("suspect = %+v", s)
+	}
+
+	// without rollover detection every decrease is suspect
+	mv = NewMonotonicValidator(0, nil)
+	mv.Observe(entry(1, 0xfffffff0), now)
+	if mv.Observe(entry(1, 0x10), now) {
+		t.Error("rollover accepted with maxStep 0")
+	}
+}
+
+func TestMonotonicValidator_WideRegisters(t *testing.T) {
+	for _, width := range []int{6, 8} {
+		entry := func(value uint64) *ListEntry {
+			le, err := ListEntryParse(&Buffer{Bytes: smlListEntryWidth([]byte{1, 0, 1, 8, 0, 255}, width, value)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return le
+		}
+		var suspects int
+		mv := NewMonotonicValidator(100, func(SuspectReading) { suspects++ })
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		mv.Observe(entry(1000000), now)
+		if mv.Observe(entry(10), now) {
+			t.Errorf("%d bytes: decrease from 1000000 to 10 accepted", width)
+		}
+		if suspects != 1 {
+			t.Errorf("%d bytes: got %d suspects, want 1", width, suspects)
+		}
+
+		mv = NewMonotonicValidator(100, func(SuspectReading) { suspects++ })
+		mv.Observe(entry(1<<(8*uint(width))-10), now)
+		if !mv.Observe(entry(5), now) {
+			t.Errorf("%d bytes: rollover by 15 flagged", width)
+		}
+		if suspects != 1 {
+			t.Errorf("%d bytes: got %d suspects, want 1", width, suspects)
+		}
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Helpers
 // ---------------------------------------------------------------------------
@@ -876,3 +5036,64 @@ func buildSMLFrame(payload []byte) []byte {
 	frame = append(frame, end...)
 	return frame
 }
+
+// smlMessage wraps a message body (tag + data) into an SML message with a valid CRC.
+func smlMessage(tag uint32, data []byte) []byte {
+	msg := []byte{0x76, 0x02, 0x01, 0x62, 0x00, 0x62, 0x00, 0x72,
+		0x65, byte(tag >> 24), byte(tag >> 16), byte(tag >> 8), byte(tag)}
+	msg = append(msg, data...)
+	crc := crc16Calculate(msg, len(msg))
+	return append(msg, 0x63, byte(crc>>8), byte(crc), 0x00)
+}
+
+// smlListEntryWidth encodes a list entry in Wh with an unsigned value of width bytes and skipped
+// status, valTime and signature.
+func smlListEntryWidth(obis []byte, width int, value uint64) []byte {
+	entry := []byte{0x77, byte(len(obis) + 1)}
+	entry = append(entry, obis...)
+	entry = append(entry, 0x01, 0x01, 0x62, UNIT_WATT_HOUR, 0x52, 0x00, 0x60|byte(width+1))
+	for i := width - 1; i >= 0; i-- {
+		entry = append(entry, byte(value>>(8*uint(i))))
+	}
+	return append(entry, 0x01)
+}
+
+// smlListEntry encodes a list entry with an u32 value and skipped status, valTime and signature.
+func smlListEntry(obis []byte, unit uint8, scaler int8, value uint32) []byte {
+	entry := []byte{0x77, byte(len(obis) + 1)}
+	entry = append(entry, obis...)
+	entry = append(entry, 0x01, 0x01, 0x62, unit, 0x52, byte(scaler),
+		0x65, byte(value>>24), byte(value>>16), byte(value>>8), byte(value), 0x01)
+	return entry
+}
+
+// smlGetListResponse encodes a GetListResponse message with the given (at most 15) entries.
+func smlGetListResponse(entries ...[]byte) []byte {
+	data := []byte{0x77, 0x01, 0x03, 0x01, 0x02, 0x01, 0x01, byte(0x70 | len(entries))}
+	for _, e := range entries {
+		data = append(data, e...)
+	}
+	data = append(data, 0x01, 0x01)
+	return smlMessage(MESSAGE_GET_LIST_RESPONSE, data)
+}
+
+// smlProfileListResponse encodes a GetProfileListResponse message for the period valTime with
+// entries for 1.8.0 and 2.8.0 in Wh with scaler -1.
+func smlProfileListResponse(valTime uint32, import180, export280 uint32) []byte {
+	data := []byte{0x79, 0x03, 0x01, 0x02,
+		0x72, 0x62, 0x01, 0x65, 0x00, 0x00, 0x10, 0x00, // actTime
+		0x65, 0x00, 0x00, 0x03, 0x84, // regPeriod 900s
+		0x71, 0x03, 0xaa, 0xbb, // parameterTreePath
+		0x72, 0x62, 0x02, 0x65, byte(valTime >> 24), byte(valTime >> 16), byte(valTime >> 8), byte(valTime),
+		0x62, 0x00, // status
+		0x72}
+	for _, pe := range []struct {
+		c     byte
+		value uint32
+	}{{1, import180}, {2, export280}} {
+		data = append(data, 0x75, 0x07, 1, 0, pe.c, 8, 0, 255, 0x62, UNIT_WATT_HOUR, 0x52, 0xff,
+			0x65, byte(pe.value>>24), byte(pe.value>>16), byte(pe.value>>8), byte(pe.value), 0x01)
+	}
+	data = append(data, 0x01, 0x01)
+	return smlMessage(MESSAGE_GET_PROFILE_LIST_RESPONSE, data)
+}
@@ -0,0 +1,96 @@
+package gosml
+
+import (
+	"sync"
+)
+
+// History merges the latest current values of GetListResponses with the recent points of
+// GetProfileListResponses into one time-ordered series per OBIS code, i.e. "latest value plus recent
+// history". Feed it with
+//
+//	WithGetListResponseCallback(h.AddList), WithGetProfileListResponseCallback(h.AddProfile)
+//
+// It is safe for concurrent use. Like TimeSeriesPoint.Time the times of different time kinds aren't
+// comparable, so a meter should be fed in one kind.
+type History struct {
+	mu      sync.Mutex
+	limit   int
+	profile map[string]TimeSeries
+	current map[string]TimeSeriesPoint
+}
+
+// NewHistory returns a history keeping up to limit profile points per OBIS code, dropping the
+// oldest ones first. A limit of 0 keeps all points.
+func NewHistory(limit int) *History {
+	return &History{
+		limit:   limit,
+		profile: map[string]TimeSeries{},
+		current: map[string]TimeSeriesPoint{},
+	}
+}
+
+// AddList stores the numeric entries of list as latest current values, stamped with their valTime or
+// the list's ActSensorTime if they have none or one of unknown kind
+func (h *History) AddList(list GetListResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, elem := range list.ValList {
+		if len(elem.ObjName) < 6 || !elem.IsNumeric() {
+			continue
+		}
+		t := elem.valTime
+		if !elem.valTimeKind.known() {
+			t = list.ActSensorTime
+		}
+		h.current[elem.ObjectName()] = TimeSeriesPoint{Time: t, Value: elem.Float()}
+	}
+}
+
+// AddProfile adds the numeric values of the period list of resp as profile points stamped with its
+// ValTime, see ProfileSeries. A point replaces an existing one of the same time, so periods sent
+// repeatedly are kept once.
+func (h *History) AddProfile(resp GetProfileListResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, pe := range resp.PeriodList {
+		if len(pe.ObjName) < 6 || !pe.isNumeric() {
+			continue
+		}
+		obis := obisString(pe.ObjName)
+		series := h.profile[obis]
+		for _, point := range ProfileSeries(&resp, pe.ObjName) {
+			series = series.replace(point)
+		}
+		series.SortByTime()
+		if h.limit > 0 && len(series) > h.limit {
+			series = append(TimeSeries{}, series[len(series)-h.limit:]...)
+		}
+		h.profile[obis] = series
+	}
+}
+
+// replace replaces the point of the series with the time of point, or appends point if there is none
+func (s TimeSeries) replace(point TimeSeriesPoint) TimeSeries {
+	for i := range s {
+		if s[i].Time == point.Time {
+			s[i] = point
+			return s
+		}
+	}
+	return append(s, point)
+}
+
+// Series returns the profile points of the OBIS code, e.g. "1-0:1.8.0*255", followed by its latest
+// current value, oldest first. Profile points newer than the current value are placed after it.
+func (h *History) Series(obis string) TimeSeries {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	profile := h.profile[obis]
+	series := make(TimeSeries, len(profile), len(profile)+1)
+	copy(series, profile)
+	if cur, ok := h.current[obis]; ok {
+		series = append(series, cur)
+		series.SortByTime()
+	}
+	return series
+}
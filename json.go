@@ -0,0 +1,36 @@
+package gosml
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type listEntryJSON struct {
+	Obis  string      `json:"obis"`
+	Value interface{} `json:"value"`
+	Unit  string      `json:"unit,omitempty"`
+}
+
+// MarshalJSON encodes the entry as object with its OBIS code, unit and value. Numeric values are
+// scaled, octet strings are encoded as hex string.
+func (le *ListEntry) MarshalJSON() ([]byte, error) {
+	entry := listEntryJSON{
+		Unit: le.UnitString(),
+	}
+	if len(le.ObjName) >= 6 {
+		entry.Obis = le.ObjectName()
+	} else {
+		entry.Obis = fmt.Sprintf("%x", []byte(le.ObjName))
+	}
+
+	switch {
+	case le.IsNumeric():
+		entry.Value = le.Float()
+	case le.Value.Typ == OCTET_TYPE_BOOLEAN:
+		entry.Value = le.Value.DataBoolean
+	case le.Value.DataBytes != nil:
+		entry.Value = fmt.Sprintf("%x", []byte(le.Value.DataBytes))
+	}
+
+	return json.Marshal(entry)
+}
@@ -0,0 +1,49 @@
+package gosml
+
+// ListKind classifies a GetListResponse by its list name
+type ListKind uint8
+
+const (
+	LIST_KIND_UNKNOWN    ListKind = iota
+	LIST_KIND_CURRENT             // current values, 1-0:99.1.0
+	LIST_KIND_HISTORICAL          // values of past billing periods, 0-0:98.1.x
+	LIST_KIND_EVENTS              // event log, 1-0:99.98.x
+)
+
+func (k ListKind) String() string {
+	switch k {
+	case LIST_KIND_CURRENT:
+		return "current values"
+	case LIST_KIND_HISTORICAL:
+		return "historical"
+	case LIST_KIND_EVENTS:
+		return "events"
+	}
+	return "unknown"
+}
+
+// ListName is the list name of a GetListResponse, e.g. 1-0:99.1.0*255 for current values
+type ListName OctetString
+
+// Kind classifies the list name by its OBIS groups C and D. Groups A, B and F vary between meters
+// and are ignored. Absent and unknown list names are LIST_KIND_UNKNOWN.
+func (name ListName) Kind() ListKind {
+	_, _, c, d, e, _, ok := OctetString(name).ObisFields()
+	if !ok {
+		return LIST_KIND_UNKNOWN
+	}
+	switch {
+	case c == 99 && d == 1 && e == 0:
+		return LIST_KIND_CURRENT
+	case c == 98 && d == 1:
+		return LIST_KIND_HISTORICAL
+	case c == 99 && d == 98:
+		return LIST_KIND_EVENTS
+	}
+	return LIST_KIND_UNKNOWN
+}
+
+// ListKind classifies the list by its list name, see ListName.Kind
+func (list *GetListResponse) ListKind() ListKind {
+	return ListName(list.ListName).Kind()
+}
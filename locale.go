@@ -0,0 +1,60 @@
+package gosml
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DecimalStyle selects the separators of LocalizedValueString
+type DecimalStyle uint8
+
+const (
+	DECIMAL_STYLE_ENGLISH DecimalStyle = iota // 1,234.5
+	DECIMAL_STYLE_GERMAN                      // 1.234,5
+)
+
+// separators returns the thousands and the decimal separator of the style
+func (style DecimalStyle) separators() (thousands, decimal string) {
+	if style == DECIMAL_STYLE_GERMAN {
+		return ".", ","
+	}
+	return ",", "."
+}
+
+// LocalizedValueString formats numeric values for display with the separators of style, e.g.
+// "1.234,5 kWh" in German style. Like in DisplayString the number of decimals is derived from the
+// scaler and energy registers are shown in kWh, kvarh or kVAh. Other values are formatted like in
+// ValueString.
+func (le *ListEntry) LocalizedValueString(style DecimalStyle) string {
+	if !le.IsNumeric() {
+		return le.ValueString()
+	}
+
+	value, decimals, unit := le.displayValue()
+	thousands, decimal := style.separators()
+
+	digits := strconv.FormatFloat(value, 'f', decimals, 64)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	integer, fraction, _ := strings.Cut(digits, ".")
+
+	var sb strings.Builder
+	sb.WriteString(sign)
+	for i, c := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			sb.WriteString(thousands)
+		}
+		sb.WriteRune(c)
+	}
+	if fraction != "" {
+		sb.WriteString(decimal)
+		sb.WriteString(fraction)
+	}
+	if unit != "" {
+		sb.WriteString(" ")
+		sb.WriteString(unit)
+	}
+	return sb.String()
+}
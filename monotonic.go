@@ -0,0 +1,80 @@
+package gosml
+
+import (
+	"sync"
+	"time"
+)
+
+// SuspectReading reports a cumulative register that decreased, see MonotonicValidator
+type SuspectReading struct {
+	Obis string
+	Prev float64
+	Cur  float64
+	Time time.Time
+}
+
+// MonotonicValidator tracks the last value of cumulative registers, i.e. registers with OBIS D-field
+// 8 like 1-0:1.8.0, and flags readings that decreased as suspect, as they indicate corrupted data or
+// a replaced meter. It is safe for concurrent use.
+type MonotonicValidator struct {
+	mu        sync.Mutex
+	maxStep   int64
+	onSuspect func(SuspectReading)
+	last      map[string]monotonicValue
+}
+
+// monotonicValue is the last value of a register. Only the number and the encoded width are kept of
+// the raw value, so that the parsed file isn't referenced.
+type monotonicValue struct {
+	raw    int64
+	width  int
+	scaled float64
+}
+
+// NewMonotonicValidator returns a validator calling onSuspect for decreased readings. Decreases
+// whose raw delta assuming wraparound at the register's width (see Registry.Delta) is positive and
+// at most maxStep are taken as rollover and not flagged. A maxStep of 0 flags every decrease.
+func NewMonotonicValidator(maxStep int64, onSuspect func(SuspectReading)) *MonotonicValidator {
+	return &MonotonicValidator{
+		maxStep:   maxStep,
+		onSuspect: onSuspect,
+		last:      map[string]monotonicValue{},
+	}
+}
+
+// Update validates le read now. It can be passed to WithObisCallback directly.
+func (mv *MonotonicValidator) Update(le *ListEntry) {
+	mv.Observe(le, time.Now())
+}
+
+// Observe validates le read at t and reports whether it is plausible. Entries of other registers or
+// with non-numeric values are always plausible. le becomes the reference for the next reading of
+// its register even if it is suspect, so a replaced meter is only flagged once.
+func (mv *MonotonicValidator) Observe(le *ListEntry, t time.Time) bool {
+	if _, _, _, d, _, _, ok := le.ObisFields(); !ok || d != 8 || !le.IsNumeric() {
+		return true
+	}
+	obis := le.ObjectName()
+
+	cur := monotonicValue{raw: le.Value.DataInt, width: le.Value.registerWidth(), scaled: le.Float()}
+
+	mv.mu.Lock()
+	prev, found := mv.last[obis]
+	mv.last[obis] = cur
+	mv.mu.Unlock()
+	if !found || cur.raw >= prev.raw {
+		return true
+	}
+
+	width := cur.width
+	if prev.width > width {
+		width = prev.width
+	}
+	if delta := wrapDelta(prev.raw, cur.raw, width); delta > 0 && delta <= mv.maxStep {
+		return true
+	}
+	if mv.onSuspect != nil {
+		mv.onSuspect(SuspectReading{Obis: obis, Prev: prev.scaled, Cur: cur.scaled, Time: t})
+	}
+	return false
+}
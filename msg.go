@@ -23,12 +23,57 @@ const (
 	MESSAGE_ATTENTION_RESPONSE          = 0x0000FF01
 )
 
+// MessageTag identifies the type of an SML message, e.g. MESSAGE_GET_LIST_RESPONSE
+type MessageTag uint32
+
+var messageTagNames = map[MessageTag]string{
+	MESSAGE_OPEN_REQUEST:                "OpenRequest",
+	MESSAGE_OPEN_RESPONSE:               "OpenResponse",
+	MESSAGE_CLOSE_REQUEST:               "CloseRequest",
+	MESSAGE_CLOSE_RESPONSE:              "CloseResponse",
+	MESSAGE_GET_PROFILE_PACK_REQUEST:    "GetProfilePackRequest",
+	MESSAGE_GET_PROFILE_PACK_RESPONSE:   "GetProfilePackResponse",
+	MESSAGE_GET_PROFILE_LIST_REQUEST:    "GetProfileListRequest",
+	MESSAGE_GET_PROFILE_LIST_RESPONSE:   "GetProfileListResponse",
+	MESSAGE_GET_PROC_PARAMETER_REQUEST:  "GetProcParameterRequest",
+	MESSAGE_GET_PROC_PARAMETER_RESPONSE: "GetProcParameterResponse",
+	MESSAGE_SET_PROC_PARAMETER_REQUEST:  "SetProcParameterRequest",
+	MESSAGE_SET_PROC_PARAMETER_RESPONSE: "SetProcParameterResponse",
+	MESSAGE_GET_LIST_REQUEST:            "GetListRequest",
+	MESSAGE_GET_LIST_RESPONSE:           "GetListResponse",
+	MESSAGE_ATTENTION_RESPONSE:          "AttentionResponse",
+}
+
+// String returns the name of the message type, e.g. "GetListResponse", or the tag in hex for
+// unknown types
+func (tag MessageTag) String() string {
+	if name, ok := messageTagNames[tag]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%08x", uint32(tag))
+}
+
+// ErrCrcMismatch means that the checksum of a message doesn't match its data
+var ErrCrcMismatch = errors.New("crc error")
+
 type Message struct {
 	TransactionID OctetString
 	GroupID       uint8
 	AbortOnError  uint8
 	MessageBody   MessageBody
 	Crc           uint16
+	CrcUnverified bool // checksum mismatched but the message was kept, see WithCrcWarn
+}
+
+// GroupNo returns the message's groupNo, i.e. GroupID under its name in the spec
+func (msg *Message) GroupNo() uint8 {
+	return msg.GroupID
+}
+
+// String returns a one line summary of the message header, e.g.
+// "GetListResponse transactionId 05f12cad07 group 0 crc 1a2b"
+func (msg *Message) String() string {
+	return fmt.Sprintf("%s transactionId %x group %d crc %04x", MessageTag(msg.MessageBody.Tag), []byte(msg.TransactionID), msg.GroupID, msg.Crc)
 }
 
 type MessageBody struct {
@@ -39,7 +84,7 @@ type MessageBody struct {
 type MessageBodyData interface{}
 
 func MessageParse(buf *Buffer, validate ...bool) (*Message, error) {
-	// debug(buf, "MessageParse")
+	buf.Debug()
 
 	msg := &Message{}
 	var err error
@@ -78,8 +123,10 @@ func MessageParse(buf *Buffer, validate ...bool) (*Message, error) {
 		//		fmt.Printf("%04x-%04x\n", crc, msg.Crc)
 
 		if crc != msg.Crc {
-			err := errors.New("crc error")
-			return msg, err
+			if !buf.crcWarn {
+				return msg, fmt.Errorf("%w: %04x (expected %04x)", ErrCrcMismatch, msg.Crc, crc)
+			}
+			msg.CrcUnverified = true
 		}
 	}
 
@@ -91,6 +138,8 @@ func MessageParse(buf *Buffer, validate ...bool) (*Message, error) {
 }
 
 func MessageBodyParse(buf *Buffer) (MessageBody, error) {
+	buf.Debug()
+
 	body := MessageBody{}
 	var err error
 
@@ -116,25 +165,19 @@ func MessageBodyParse(buf *Buffer) (MessageBody, error) {
 		body.Data, err = CloseResponseParse(buf)
 		return body, err
 	case MESSAGE_GET_PROFILE_PACK_REQUEST:
-		return body, fmt.Errorf("unimplemented message type MESSAGE_GET_PROFILE_PACK_REQUEST")
 		// msgBody->data = GetProfilePackRequestParse(buf);
 	case MESSAGE_GET_PROFILE_PACK_RESPONSE:
-		return body, fmt.Errorf("unimplemented message type MESSAGE_GET_PROFILE_PACK_RESPONSE")
 		// msgBody->data = GetProfilePackResponseParse(buf);
 	case MESSAGE_GET_PROFILE_LIST_REQUEST:
-		return body, fmt.Errorf("unimplemented message type MESSAGE_GET_PROFILE_LIST_REQUEST")
 		// msgBody->data = GetProfileListRequestParse(buf);
 	case MESSAGE_GET_PROFILE_LIST_RESPONSE:
-		return body, fmt.Errorf("unimplemented message type MESSAGE_GET_PROFILE_LIST_RESPONSE")
-		// msgBody->data = GetProfileListResponseParse(buf);
+		body.Data, err = GetProfileListResponseParse(buf)
+		return body, err
 	case MESSAGE_GET_PROC_PARAMETER_REQUEST:
-		return body, fmt.Errorf("unimplemented message type MESSAGE_GET_PROC_PARAMETER_REQUEST")
 		// msgBody->data = GetProcParameterRequestParse(buf);
 	case MESSAGE_GET_PROC_PARAMETER_RESPONSE:
-		return body, fmt.Errorf("unimplemented message type MESSAGE_GET_PROC_PARAMETER_RESPONSE")
 		// msgBody->data = GetProcParameterResponseParse(buf);
 	case MESSAGE_SET_PROC_PARAMETER_REQUEST:
-		return body, fmt.Errorf("unimplemented message type MESSAGE_SET_PROC_PARAMETER_REQUEST")
 		// msgBody->data = SetProcParameterRequestParse(buf);
 	case MESSAGE_GET_LIST_REQUEST:
 		body.Data, err = GetListRequestParse(buf)
@@ -143,9 +186,74 @@ func MessageBodyParse(buf *Buffer) (MessageBody, error) {
 		body.Data, err = GetListResponseParse(buf)
 		return body, err
 	case MESSAGE_ATTENTION_RESPONSE:
-		return body, fmt.Errorf("unimplemented message type MESSAGE_ATTENTION_RESPONSE")
-		// msgBody->data = AttentionResponseParse(buf);
+		body.Data, err = AttentionResponseParse(buf)
+		return body, err
 	}
 
-	return body, fmt.Errorf("invalid message type: % x", body.Tag)
+	// unimplemented and unknown message types, e.g. vendor specific ones, are skipped as a whole so
+	// that the following messages of the file can still be parsed. Data is left nil.
+	if err := buf.skipElement(); err != nil {
+		return body, fmt.Errorf("message type % x: %w", body.Tag, err)
+	}
+	return body, nil
+}
+
+// MessageTags returns the types of the messages of frame in order, e.g. to check the structure of a
+// meter's files. Only the message headers are parsed, bodies are skipped without being decoded and
+// checksums aren't verified. frame may be a complete SML file including begin and end sequences or
+// a payload as passed to ParsePayload.
+func MessageTags(frame []byte) (tags []MessageTag, err error) {
+	payload, err := deframe(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("message %d: parse panic", len(tags))
+		}
+	}()
+
+	buf := &Buffer{Bytes: payload}
+	for buf.Cursor < len(buf.Bytes) {
+		if buf.GetCurrentByte() == OCTET_MESSAGE_END {
+			// end of message or trailing padding
+			buf.UpdateBytesRead(1)
+			continue
+		}
+		tag, err := buf.messageTagParse()
+		if err != nil {
+			return tags, fmt.Errorf("message %d: %w", len(tags), err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// messageTagParse parses the tag of the next message and skips the rest of the message except for
+// its end of message byte
+func (buf *Buffer) messageTagParse() (MessageTag, error) {
+	if err := buf.Expect(OCTET_TYPE_LIST, 6); err != nil {
+		return 0, err
+	}
+	// transactionId, groupNo and abortOnError
+	for i := 0; i < 3; i++ {
+		if err := buf.skipElement(); err != nil {
+			return 0, err
+		}
+	}
+	if err := buf.Expect(OCTET_TYPE_LIST, 2); err != nil {
+		return 0, fmt.Errorf("messageBody: %w", err)
+	}
+	tag, err := buf.U32Parse()
+	if err != nil {
+		return 0, fmt.Errorf("messageBody: %w", err)
+	}
+	// body and crc
+	for i := 0; i < 2; i++ {
+		if err := buf.skipElement(); err != nil {
+			return MessageTag(tag), err
+		}
+	}
+	return MessageTag(tag), nil
 }
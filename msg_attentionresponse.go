@@ -0,0 +1,103 @@
+package gosml
+
+import (
+	"bytes"
+	"fmt"
+)
+
+type AttentionResponse struct {
+	ServerID         OctetString
+	AttentionNumber  OctetString
+	AttentionMessage OctetString // optional
+	AttentionDetails *Tree       // optional
+}
+
+func AttentionResponseParse(buf *Buffer) (AttentionResponse, error) {
+	buf.Debug()
+
+	msg := AttentionResponse{}
+	var err error
+
+	if err := buf.Expect(OCTET_TYPE_LIST, 4); err != nil {
+		return msg, err
+	}
+
+	if msg.ServerID, err = buf.OctetStringParse(); err != nil {
+		return msg, fmt.Errorf("serverId: %w", err)
+	}
+
+	if msg.AttentionNumber, err = buf.OctetStringParse(); err != nil {
+		return msg, fmt.Errorf("attentionNo: %w", err)
+	}
+
+	if msg.AttentionMessage, err = buf.OctetStringParse(); err != nil {
+		return msg, fmt.Errorf("attentionMsg: %w", err)
+	}
+
+	if msg.AttentionDetails, err = TreeParse(buf); err != nil {
+		return msg, fmt.Errorf("attentionDetails: %w", err)
+	}
+
+	return msg, nil
+}
+
+// attentionPrefix is common to all attention numbers defined by the SML specification
+var attentionPrefix = OctetString{0x81, 0x81, 0xc7, 0xc7}
+
+var attentionDescriptions = map[uint16]string{
+	0xfd00: "ok",
+	0xfd01: "ok, attention will be sent later",
+	0xfe00: "error not listed in the specification",
+	0xfe01: "unknown SML designator",
+	0xfe02: "insufficient authentication",
+	0xfe03: "server id not available",
+	0xfe04: "reqFileId not available",
+	0xfe05: "one or more destination attributes cannot be written",
+	0xfe06: "one or more destination attributes cannot be read",
+	0xfe07: "communication with metering point disrupted",
+	0xfe08: "raw data cannot be interpreted",
+	0xfe09: "value out of permitted range",
+	0xfe0a: "request not executed (e.g. parameterTreePath doesn't exist)",
+	0xfe0b: "checksum faulty",
+	0xfe0c: "broadcast not supported",
+	0xfe0d: "unexpected SML message (e.g. SML file without open request)",
+	0xfe0e: "unknown object in the load profile",
+	0xfe0f: "data type not supported",
+	0xfe10: "optional element not supported",
+	0xfe11: "requested load profile has no entry",
+	0xfe12: "load profile request with endTime before beginTime",
+	0xfe13: "no entries in the requested time range",
+	0xfe14: "SML file without close",
+	0xfe15: "load profile cannot be delivered at present",
+}
+
+// AttentionCode renders the attention number in hex like ObjectNameHex, e.g. "81-81:C7.C7.FD*00"
+func (msg *AttentionResponse) AttentionCode() string {
+	return (&ListEntry{ObjName: msg.AttentionNumber}).ObjectNameHex()
+}
+
+// Description returns a description of the standard attention numbers or "unknown attention
+// number" followed by the code for others
+func (msg *AttentionResponse) Description() string {
+	if code, ok := msg.standardCode(); ok {
+		if description, ok := attentionDescriptions[code]; ok {
+			return description
+		}
+	}
+	return "unknown attention number " + msg.AttentionCode()
+}
+
+// IsError reports whether the attention number is one of the standard error numbers
+// 81 81 C7 C7 FE xx
+func (msg *AttentionResponse) IsError() bool {
+	code, ok := msg.standardCode()
+	return ok && code>>8 == 0xfe
+}
+
+func (msg *AttentionResponse) standardCode() (uint16, bool) {
+	n := msg.AttentionNumber
+	if len(n) != 6 || !bytes.HasPrefix(n, attentionPrefix) {
+		return 0, false
+	}
+	return uint16(n[4])<<8 | uint16(n[5]), true
+}
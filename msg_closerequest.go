@@ -1,5 +1,7 @@
 package gosml
 
+import "fmt"
+
 type CloseRequest struct {
 	GlobalSignature OctetString
 }
@@ -13,7 +15,7 @@ func CloseRequestParse(buf *Buffer) (CloseRequest, error) {
 	}
 
 	if msg.GlobalSignature, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("globalSignature: %w", err)
 	}
 
 	return msg, nil
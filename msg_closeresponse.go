@@ -1,8 +1,12 @@
 package gosml
 
+import "fmt"
+
 type CloseResponse CloseRequest
 
 func CloseResponseParse(buf *Buffer) (CloseResponse, error) {
+	buf.Debug()
+
 	msg := CloseResponse{}
 	var err error
 
@@ -11,7 +15,7 @@ func CloseResponseParse(buf *Buffer) (CloseResponse, error) {
 	}
 
 	if msg.GlobalSignature, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("globalSignature: %w", err)
 	}
 
 	return msg, nil
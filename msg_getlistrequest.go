@@ -1,5 +1,10 @@
 package gosml
 
+import "fmt"
+
+// GetListRequest requests a list from a meter. Some meters require Username and Password to return
+// certain lists. Note that SML transmits them in plaintext, so they can be read by anyone with
+// access to the line and should not be reused elsewhere.
 type GetListRequest struct {
 	ClientID OctetString
 	ServerID OctetString // optional
@@ -17,23 +22,23 @@ func GetListRequestParse(buf *Buffer) (GetListRequest, error) {
 	}
 
 	if msg.ClientID, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("clientId: %w", err)
 	}
 
 	if msg.ServerID, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("serverId: %w", err)
 	}
 
 	if msg.Username, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("username: %w", err)
 	}
 
 	if msg.Password, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("password: %w", err)
 	}
 
 	if msg.ListName, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("listName: %w", err)
 	}
 
 	return msg, nil
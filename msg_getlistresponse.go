@@ -1,8 +1,12 @@
 package gosml
 
 import (
+	"bytes"
 	"fmt"
 	"math"
+	"math/big"
+	"strings"
+	"time"
 )
 
 type GetListResponse struct {
@@ -13,20 +17,88 @@ type GetListResponse struct {
 	ValList        []*ListEntry
 	ListSignature  OctetString
 	ActGatewayTime Time
+
+	actSensorTimeKind   TimeKind
+	actSensorTimeOffset int
+	actGatewayTimeKind  TimeKind
+	actGatewayOffset    int
+	hasListSignature    bool
+	location            *time.Location // see WithTimezone
+}
+
+// HasListSignature reports whether the list's signature was present. Note that SML encodes an empty
+// octet string like a skipped optional, so an empty signature is reported as absent.
+func (list *GetListResponse) HasListSignature() bool {
+	return list.hasListSignature
+}
+
+// Find returns the entries whose OBIS code starts with prefix, like the codes passed to
+// WithObisCallback, e.g. OctetString{1, 0, 1, 8} for all tariffs of 1.8. An empty prefix matches all
+// entries. If no entry matches, an empty slice is returned.
+func (list *GetListResponse) Find(prefix OctetString) []*ListEntry {
+	entries := []*ListEntry{}
+	for _, elem := range list.ValList {
+		if len(elem.ObjName) > 0 && bytes.HasPrefix(elem.ObjName, prefix) {
+			entries = append(entries, elem)
+		}
+	}
+	return entries
 }
 
 type ListEntry struct {
 	ObjName        OctetString
 	status         int64
+	statusRaw      OctetString
 	valTime        Time
+	valTimeKind    TimeKind
+	valTimeOffset  int
 	Unit           uint8
 	scaler         int8
 	Value          Value
 	ValueSignature OctetString
+
+	hasStatus  bool
+	hasUnit    bool
+	hasScaler  bool
+	transform  func(float64) float64 // see WithTransform
+	location   *time.Location        // see WithTimezone
+	decoder    RegisterDecoder       // see WithRegisterDecoder
+	signedData []byte                // see SignedData
 }
 
+// ObjectName renders the entry's OBIS code as "A-B:C.D.E*F" with decimal groups, e.g.
+// "1-0:1.8.0*255". Codes that don't consist of 6 bytes are rendered in hex.
 func (le *ListEntry) ObjectName() string {
-	return fmt.Sprintf("%d-%d:%d.%d.%d*%d", le.ObjName[0], le.ObjName[1], le.ObjName[2], le.ObjName[3], le.ObjName[4], le.ObjName[5])
+	return obisString(le.ObjName)
+}
+
+// SignedData returns the encoded fields covered by the entry's value signature, i.e. the bytes from
+// objName up to and including value with their type-length fields as sent by the meter, or nil if
+// the entry isn't signed. Like the other raw fields the slice refers to the parsed file.
+func (le *ListEntry) SignedData() []byte {
+	return le.signedData
+}
+
+// Status returns the entry's status word and whether it was present. Status words encoded as octet
+// string are read as big endian number, StatusRaw returns the bytes as sent.
+func (le *ListEntry) Status() (int64, bool) {
+	return le.status, le.hasStatus
+}
+
+// StatusRaw returns the bytes of the entry's status word without type-length field, or nil if the
+// status was skipped
+func (le *ListEntry) StatusRaw() OctetString {
+	return le.statusRaw
+}
+
+// HasUnit reports whether the entry's unit was present. Unit is 0 for entries without unit.
+func (le *ListEntry) HasUnit() bool {
+	return le.hasUnit
+}
+
+// HasScaler reports whether the entry's scaler was present. Entries without scaler are not scaled.
+func (le *ListEntry) HasScaler() bool {
+	return le.hasScaler
 }
 
 func (le *ListEntry) Scaler() float64 {
@@ -34,33 +106,190 @@ func (le *ListEntry) Scaler() float64 {
 }
 
 func (le *ListEntry) ValueString() string {
+	return le.ValueStringf("%12.1f")
+}
+
+// ValueStringf works like ValueString but formats numeric values with the given printf verb,
+// e.g. "%g" or "%.3f". Values decoded by a RegisterDecoder are rendered with %v instead, values
+// failing to decode as usual.
+func (le *ListEntry) ValueStringf(format string) string {
+	if le.decoder != nil {
+		if v, err := le.ValueDecoded(); err == nil {
+			return fmt.Sprintf("%v", v)
+		}
+	}
 	switch le.Value.Typ {
 	case OCTET_TYPE_OCTET_STRING:
 		return fmt.Sprintf("% x", le.Value.DataBytes)
 	case OCTET_TYPE_BOOLEAN:
 		return fmt.Sprintf("%v", le.Value.DataBoolean)
+	case OCTET_TYPE_LIST:
+		values := le.ValueList()
+		elems := make([]string, len(values))
+		for i, v := range values {
+			elems[i] = strings.TrimSpace(fmt.Sprintf(format, v))
+		}
+		return "(" + strings.Join(elems, ", ") + ")"
 	default:
 		if ((le.Value.Typ & OCTET_TYPE_FIELD) == OCTET_TYPE_INTEGER) || ((le.Value.Typ & OCTET_TYPE_FIELD) == OCTET_TYPE_UNSIGNED) {
-			value := float64(le.Value.DataInt) * le.Scaler()
-			return fmt.Sprintf("%12.1f", value)
+			return fmt.Sprintf(format, le.Float())
 		}
 	}
 	return ""
 }
 
+// ValueList returns the elements of compound values, e.g. registers sending a list of two numbers,
+// scaled and transformed like in Float. Elements that aren't numbers are NaN. ValueList returns nil
+// for other values.
+func (le *ListEntry) ValueList() []float64 {
+	if !le.Value.IsCompound() {
+		return nil
+	}
+	values := []float64{}
+	buf := &Buffer{Bytes: le.Value.Raw}
+	for buf.Cursor < len(buf.Bytes) {
+		value := math.NaN()
+		switch typeField := buf.GetNextType(); {
+		case buf.GetCurrentByte() == OCTET_OPTIONAL_SKIPPED:
+			buf.UpdateBytesRead(1)
+		case typeField == OCTET_TYPE_INTEGER || typeField == OCTET_TYPE_UNSIGNED:
+			num, err := buf.NumberParse(typeField, TYPE_NUMBER_64)
+			if err != nil {
+				return values
+			}
+			value = float64(num) * le.Scaler()
+			if le.transform != nil {
+				value = le.transform(value)
+			}
+		default:
+			if err := buf.skipElement(); err != nil {
+				return values
+			}
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// Float returns the scaled value of numeric entries, with the transform registered by WithTransform
+// applied, or 0 for other entries. Value.DataInt always holds the raw value.
 func (le *ListEntry) Float() float64 {
 	if ((le.Value.Typ & OCTET_TYPE_FIELD) == OCTET_TYPE_INTEGER) || ((le.Value.Typ & OCTET_TYPE_FIELD) == OCTET_TYPE_UNSIGNED) {
 		value := float64(le.Value.DataInt) * le.Scaler()
+		if le.transform != nil {
+			value = le.transform(value)
+		}
 		return value
 	}
 	return 0.0
 }
 
+// Rat returns the exact scaled value of numeric entries as a rational, allowing to sum up many
+// values without rounding errors. Transforms registered by WithTransform are not applied. Rat
+// returns nil for other entries.
+func (le *ListEntry) Rat() *big.Rat {
+	if !le.IsNumeric() {
+		return nil
+	}
+	exp := int64(le.scaler)
+	if exp < 0 {
+		exp = -exp
+	}
+	r := new(big.Rat).SetInt64(le.Value.DataInt)
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(exp), nil)
+	if le.scaler < 0 {
+		return r.Quo(r, new(big.Rat).SetInt(pow))
+	}
+	return r.Mul(r, new(big.Rat).SetInt(pow))
+}
+
+// DisplayString formats numeric values like they are shown on the meter's display, e.g.
+// "012345.6789 kWh": the integer part is padded to 6 digits, the number of decimals is derived from
+// the scaler and energy registers are shown in kWh, kvarh or kVAh. Other values are formatted like
+// in ValueString.
+func (le *ListEntry) DisplayString() string {
+	return le.DisplayStringDigits(6)
+}
+
+// DisplayStringDigits works like DisplayString but pads the integer part to the given number of
+// digits
+func (le *ListEntry) DisplayStringDigits(digits int) string {
+	if !le.IsNumeric() {
+		return le.ValueString()
+	}
+
+	value, decimals, unit := le.displayValue()
+	width := digits
+	if decimals > 0 {
+		width += decimals + 1
+	}
+
+	str := fmt.Sprintf("%0*.*f", width, decimals, value)
+	if unit != "" {
+		str += " " + unit
+	}
+	return str
+}
+
+// displayValue returns the value of a numeric entry as shown on the meter's display along with the
+// number of decimals derived from the scaler and the unit, see DisplayString
+func (le *ListEntry) displayValue() (value float64, decimals int, unit string) {
+	value = le.Float()
+	decimals = -int(le.scaler)
+	unit = le.UnitString()
+
+	switch le.Unit {
+	case UNIT_WATT_HOUR, UNIT_VAR_HOUR, UNIT_VA_HOUR:
+		value /= 1000
+		decimals += 3
+		unit = "k" + unit
+	}
+
+	if decimals < 0 {
+		decimals = 0
+	}
+	return value, decimals, unit
+}
+
+// Duration converts the scaled value of entries with a time unit (seconds, minutes, hours or
+// days) to a time.Duration. It returns false for entries with other units or non-numeric values and
+// for values exceeding the range of time.Duration, about 292 years.
+func (le *ListEntry) Duration() (time.Duration, bool) {
+	var unit time.Duration
+	switch le.Unit {
+	case UNIT_SECOND:
+		unit = time.Second
+	case UNIT_MINUTE:
+		unit = time.Minute
+	case UNIT_HOUR:
+		unit = time.Hour
+	case UNIT_DAY:
+		unit = 24 * time.Hour
+	default:
+		return 0, false
+	}
+	if !le.IsNumeric() {
+		return 0, false
+	}
+	d := le.Float() * float64(unit)
+	if math.IsNaN(d) || d >= math.MaxInt64 || d < math.MinInt64 {
+		return 0, false
+	}
+	return time.Duration(d), true
+}
+
+// IsNumeric reports whether the value of the entry is an integer or unsigned number
+func (le *ListEntry) IsNumeric() bool {
+	return ((le.Value.Typ & OCTET_TYPE_FIELD) == OCTET_TYPE_INTEGER) || ((le.Value.Typ & OCTET_TYPE_FIELD) == OCTET_TYPE_UNSIGNED)
+}
+
 func (le *ListEntry) String() string {
 	return fmt.Sprintf("%-22s%s", le.ObjectName(), le.ValueString())
 }
 
 func GetListResponseParse(buf *Buffer) (GetListResponse, error) {
+	buf.Debug()
+
 	list := GetListResponse{}
 	var err error
 
@@ -69,43 +298,44 @@ func GetListResponseParse(buf *Buffer) (GetListResponse, error) {
 	}
 
 	if list.ClientID, err = buf.OctetStringParse(); err != nil {
-		return list, err
+		return list, fmt.Errorf("clientId: %w", err)
 	}
 
 	if list.ServerID, err = buf.OctetStringParse(); err != nil {
-		return list, err
+		return list, fmt.Errorf("serverId: %w", err)
 	}
 
 	if list.ListName, err = buf.OctetStringParse(); err != nil {
-		return list, err
+		return list, fmt.Errorf("listName: %w", err)
 	}
 
-	if list.ActSensorTime, err = buf.TimeParse(); err != nil {
-		return list, err
+	if list.ActSensorTime, list.actSensorTimeKind, list.actSensorTimeOffset, err = buf.TimeChoiceParse(); err != nil {
+		return list, fmt.Errorf("actSensorTime: %w", err)
 	}
 
 	if list.ValList, err = ListParse(buf); err != nil {
-		return list, err
+		return list, fmt.Errorf("valList: %w", err)
 	}
 
+	list.hasListSignature = buf.GetCurrentByte() != OCTET_OPTIONAL_SKIPPED
 	if list.ListSignature, err = buf.OctetStringParse(); err != nil {
-		return list, err
+		return list, fmt.Errorf("listSignature: %w", err)
 	}
 
-	if list.ActGatewayTime, err = buf.TimeParse(); err != nil {
-		return list, err
+	if list.ActGatewayTime, list.actGatewayTimeKind, list.actGatewayOffset, err = buf.TimeChoiceParse(); err != nil {
+		return list, fmt.Errorf("actGatewayTime: %w", err)
 	}
 
 	return list, nil
 }
 
 func ListParse(buf *Buffer) ([]*ListEntry, error) {
+	buf.Debug()
+
 	if buf.OptionalIsSkipped() {
 		return nil, nil
 	}
 
-	buf.Debug()
-
 	if err := buf.ExpectType(OCTET_TYPE_LIST); err != nil {
 		return nil, err
 	}
@@ -113,11 +343,25 @@ func ListParse(buf *Buffer) ([]*ListEntry, error) {
 	list := make([]*ListEntry, 0)
 
 	elems := buf.GetNextLength()
+	if buf.maxListEntries > 0 && elems > buf.maxListEntries {
+		return nil, fmt.Errorf("%w: %d entries (max %d)", ErrLimitExceeded, elems, buf.maxListEntries)
+	}
 
 	for elems > 0 {
+		if buf.entryFilter != nil {
+			skipped, err := buf.skipFilteredEntry()
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: %w", len(list), err)
+			}
+			if skipped {
+				elems--
+				continue
+			}
+		}
+
 		elem, err := ListEntryParse(buf)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("entry %d: %w", len(list), err)
 		}
 		list = append(list, elem)
 		elems--
@@ -126,42 +370,104 @@ func ListParse(buf *Buffer) ([]*ListEntry, error) {
 	return list, nil
 }
 
+// skipFilteredEntry skips the next list entry without parsing its fields if the entry filter rejects
+// its objName. Entries that are accepted or malformed are left to ListEntryParse.
+func (buf *Buffer) skipFilteredEntry() (bool, error) {
+	start := buf.Cursor
+	if buf.GetNextType() != OCTET_TYPE_LIST {
+		return false, nil
+	}
+	length := buf.GetNextLength()
+	if length < 1 || length > 7 {
+		buf.Cursor = start
+		return false, nil
+	}
+	objName, err := buf.ObjNameParse()
+	if err != nil || buf.entryFilter(objName) {
+		buf.Cursor = start
+		return false, nil
+	}
+	for length--; length > 0; length-- {
+		if err := buf.skipElement(); err != nil {
+			return false, fmt.Errorf("objName %x: %w", objName, err)
+		}
+	}
+	return true, nil
+}
+
+// ListEntryParse parses a list entry. Besides the regular entry with 7 fields
+// (objName, status, valTime, unit, scaler, value, valueSignature) entries with 1 to 6 fields are
+// supported: fields are parsed in order, missing trailing fields are treated as skipped optionals.
+// With WithShortListEntries entries of 5 fields are read as objName, unit, scaler, value and
+// valueSignature instead, i.e. with status and valTime omitted.
 func ListEntryParse(buf *Buffer) (*ListEntry, error) {
 	buf.Debug()
 
 	elem := ListEntry{}
 	var err error
 
-	if err := buf.Expect(OCTET_TYPE_LIST, 7); err != nil {
+	if err := buf.ExpectType(OCTET_TYPE_LIST); err != nil {
 		return &elem, err
 	}
 
-	if elem.ObjName, err = buf.OctetStringParse(); err != nil {
-		return &elem, err
+	length := buf.GetNextLength()
+	if length < 1 || length > 7 {
+		return &elem, fmt.Errorf("invalid length: %d (expected 1 to 7)", length)
 	}
 
-	if elem.status, err = buf.StatusParse(); err != nil {
-		return &elem, err
+	start := buf.Cursor
+	if elem.ObjName, err = buf.ObjNameParse(); err != nil {
+		return &elem, fmt.Errorf("objName: %w", err)
 	}
 
-	if elem.valTime, err = buf.TimeParse(); err != nil {
-		return &elem, err
+	if length == 5 && buf.shortEntries {
+		// status and valTime omitted
+		length += 2
+	} else {
+		if length > 1 {
+			elem.hasStatus = buf.GetCurrentByte() != OCTET_OPTIONAL_SKIPPED
+			if elem.status, elem.statusRaw, err = buf.StatusRawParse(); err != nil {
+				return &elem, fmt.Errorf("status: %w", err)
+			}
+		}
+
+		if length > 2 {
+			if elem.valTime, elem.valTimeKind, elem.valTimeOffset, err = buf.TimeChoiceParse(); err != nil {
+				return &elem, fmt.Errorf("valTime: %w", err)
+			}
+		}
 	}
 
-	if elem.Unit, err = buf.U8Parse(); err != nil {
-		return &elem, err
+	if length > 3 {
+		elem.hasUnit = buf.GetCurrentByte() != OCTET_OPTIONAL_SKIPPED
+		if elem.Unit, err = buf.U8Parse(); err != nil {
+			return &elem, fmt.Errorf("unit: %w", err)
+		}
 	}
 
-	if elem.scaler, err = buf.I8Parse(); err != nil {
-		return &elem, err
+	if length > 4 {
+		elem.hasScaler = buf.GetCurrentByte() != OCTET_OPTIONAL_SKIPPED
+		if elem.scaler, err = buf.I8Parse(); err != nil {
+			return &elem, fmt.Errorf("scaler: %w", err)
+		}
 	}
 
-	if elem.Value, err = buf.ValueParse(); err != nil {
-		return &elem, err
+	if length > 5 {
+		if elem.Value, err = buf.ValueParse(); err != nil {
+			return &elem, fmt.Errorf("value: %w", err)
+		}
+		if buf.maxValueLen > 0 && len(elem.Value.Raw) > buf.maxValueLen {
+			return &elem, fmt.Errorf("value: %w: %d bytes (max %d)", ErrLimitExceeded, len(elem.Value.Raw), buf.maxValueLen)
+		}
 	}
 
-	if elem.ValueSignature, err = buf.OctetStringParse(); err != nil {
-		return &elem, err
+	if length > 6 {
+		if buf.GetCurrentByte() != OCTET_OPTIONAL_SKIPPED {
+			elem.signedData = buf.Bytes[start:buf.Cursor]
+		}
+		if elem.ValueSignature, err = buf.OctetStringParse(); err != nil {
+			return &elem, fmt.Errorf("valueSignature: %w", err)
+		}
 	}
 
 	return &elem, nil
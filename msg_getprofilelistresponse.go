@@ -0,0 +1,162 @@
+package gosml
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+type GetProfileListResponse struct {
+	ServerID          OctetString
+	ActTime           Time
+	RegPeriod         uint32
+	ParameterTreePath TreePath
+	ValTime           Time
+	Status            uint64
+	PeriodList        []*PeriodEntry
+	Rawdata           OctetString // optional
+	PeriodSignature   OctetString // optional
+}
+
+// TimeSeriesPoint is a single register value of a profile period
+type TimeSeriesPoint struct {
+	Time  Time
+	Value float64
+}
+
+// TimeSeries is a series of register values, in the order the meter sent the periods
+type TimeSeries []TimeSeriesPoint
+
+// SeriesOrder describes the order of a time series' points
+type SeriesOrder uint8
+
+const (
+	SERIES_ORDER_ASCENDING  SeriesOrder = iota // oldest first
+	SERIES_ORDER_DESCENDING                    // newest first
+	SERIES_ORDER_UNORDERED
+)
+
+// Order detects the order of the series from its timestamps. Series with less than two distinct
+// timestamps are considered ascending.
+func (s TimeSeries) Order() SeriesOrder {
+	ascending, descending := false, false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i].Time > s[i-1].Time:
+			ascending = true
+		case s[i].Time < s[i-1].Time:
+			descending = true
+		}
+	}
+	switch {
+	case ascending && descending:
+		return SERIES_ORDER_UNORDERED
+	case descending:
+		return SERIES_ORDER_DESCENDING
+	}
+	return SERIES_ORDER_ASCENDING
+}
+
+// SortByTime sorts the series chronologically (oldest first) in place, regardless of whether the
+// meter sent its periods newest or oldest first. Points with equal timestamps keep their order.
+func (s TimeSeries) SortByTime() {
+	switch s.Order() {
+	case SERIES_ORDER_ASCENDING:
+	case SERIES_ORDER_DESCENDING:
+		for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+			s[i], s[j] = s[j], s[i]
+		}
+	default:
+		sort.SliceStable(s, func(i, j int) bool { return s[i].Time < s[j].Time })
+	}
+}
+
+func GetProfileListResponseParse(buf *Buffer) (GetProfileListResponse, error) {
+	msg := GetProfileListResponse{}
+	var err error
+
+	if err := buf.Expect(OCTET_TYPE_LIST, 9); err != nil {
+		return msg, err
+	}
+
+	if msg.ServerID, err = buf.OctetStringParse(); err != nil {
+		return msg, fmt.Errorf("serverId: %w", err)
+	}
+
+	if msg.ActTime, err = buf.TimeParse(); err != nil {
+		return msg, fmt.Errorf("actTime: %w", err)
+	}
+
+	if msg.RegPeriod, err = buf.U32Parse(); err != nil {
+		return msg, fmt.Errorf("regPeriod: %w", err)
+	}
+
+	if msg.ParameterTreePath, err = TreePathParse(buf); err != nil {
+		return msg, fmt.Errorf("parameterTreePath: %w", err)
+	}
+
+	if msg.ValTime, err = buf.TimeParse(); err != nil {
+		return msg, fmt.Errorf("valTime: %w", err)
+	}
+
+	if msg.Status, err = buf.U64Parse(); err != nil {
+		return msg, fmt.Errorf("status: %w", err)
+	}
+
+	if msg.PeriodList, err = PeriodListParse(buf); err != nil {
+		return msg, fmt.Errorf("periodList: %w", err)
+	}
+
+	if msg.Rawdata, err = buf.OctetStringParse(); err != nil {
+		return msg, fmt.Errorf("rawdata: %w", err)
+	}
+
+	if msg.PeriodSignature, err = buf.OctetStringParse(); err != nil {
+		return msg, fmt.Errorf("periodSignature: %w", err)
+	}
+
+	return msg, nil
+}
+
+func PeriodListParse(buf *Buffer) ([]*PeriodEntry, error) {
+	if buf.OptionalIsSkipped() {
+		return nil, nil
+	}
+
+	if err := buf.ExpectType(OCTET_TYPE_LIST); err != nil {
+		return nil, err
+	}
+
+	list := make([]*PeriodEntry, 0)
+
+	for elems := buf.GetNextLength(); elems > 0; elems-- {
+		entry, err := PeriodEntryParse(buf)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", len(list), err)
+		}
+		if entry != nil {
+			list = append(list, entry)
+		}
+	}
+
+	return list, nil
+}
+
+// ProfileSeries extracts the numeric values of the register obis from the period list of resp,
+// scaled and stamped with the period's ValTime. As every GetProfileListResponse carries a single
+// period, the series of consecutive periods is obtained by appending the results of their
+// responses. Use TimeSeries.SortByTime to get a chronological series regardless of the order the
+// meter sends.
+func ProfileSeries(resp *GetProfileListResponse, obis OctetString) TimeSeries {
+	var points TimeSeries
+	for _, pe := range resp.PeriodList {
+		if !bytes.Equal(pe.ObjName, obis) || !pe.isNumeric() {
+			continue
+		}
+		points = append(points, TimeSeriesPoint{
+			Time:  resp.ValTime,
+			Value: float64(pe.Value.DataInt) * pe.Scaler(),
+		})
+	}
+	return points
+}
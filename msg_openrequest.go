@@ -1,5 +1,7 @@
 package gosml
 
+import "fmt"
+
 type OpenRequest struct {
 	Codepage  OctetString // optional
 	ClientID  OctetString
@@ -19,31 +21,31 @@ func OpenRequestParse(buf *Buffer) (OpenRequest, error) {
 	}
 
 	if msg.Codepage, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("codepage: %w", err)
 	}
 
 	if msg.ClientID, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("clientId: %w", err)
 	}
 
 	if msg.ReqFileID, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("reqFileId: %w", err)
 	}
 
 	if msg.ServerID, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("serverId: %w", err)
 	}
 
 	if msg.Username, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("username: %w", err)
 	}
 
 	if msg.Password, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("password: %w", err)
 	}
 
 	if msg.Version, err = buf.U8Parse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("smlVersion: %w", err)
 	}
 
 	return msg, nil
@@ -1,5 +1,7 @@
 package gosml
 
+import "fmt"
+
 type OpenResponse struct {
 	Codepage  OctetString
 	ClientID  OctetString
@@ -7,9 +9,14 @@ type OpenResponse struct {
 	ServerID  OctetString
 	RefTime   Time
 	Version   uint8
+
+	refTimeKind   TimeKind
+	refTimeOffset int
 }
 
 func OpenResponseParse(buf *Buffer) (OpenResponse, error) {
+	buf.Debug()
+
 	msg := OpenResponse{}
 	var err error
 
@@ -18,28 +25,45 @@ func OpenResponseParse(buf *Buffer) (OpenResponse, error) {
 	}
 
 	if msg.Codepage, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("codepage: %w", err)
 	}
 
 	if msg.ClientID, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("clientId: %w", err)
 	}
 
 	if msg.ReqFileID, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("reqFileId: %w", err)
 	}
 
 	if msg.ServerID, err = buf.OctetStringParse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("serverId: %w", err)
 	}
 
-	if msg.RefTime, err = buf.TimeParse(); err != nil {
-		return msg, err
+	if msg.RefTime, msg.refTimeKind, msg.refTimeOffset, err = buf.TimeChoiceParse(); err != nil {
+		return msg, fmt.Errorf("refTime: %w", err)
 	}
 
 	if msg.Version, err = buf.U8Parse(); err != nil {
-		return msg, err
+		return msg, fmt.Errorf("smlVersion: %w", err)
 	}
 
 	return msg, nil
 }
+
+// SMLVersion returns the SML version announced by the meter, or 1 if the optional version is skipped
+func (msg *OpenResponse) SMLVersion() uint8 {
+	if msg.Version == 0 {
+		return 1
+	}
+	return msg.Version
+}
+
+// CodepageName returns the codepage of the octet strings sent by the meter, or "ASCII" if the
+// optional codepage is skipped
+func (msg *OpenResponse) CodepageName() string {
+	if len(msg.Codepage) == 0 {
+		return "ASCII"
+	}
+	return string(msg.Codepage)
+}
@@ -0,0 +1,151 @@
+package gosml
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseObis parses an OBIS code into its 6 byte representation. Accepted notations are
+// "A-B:C.D.E*F" (e.g. "1-0:1.8.0*255" or "129-129:199.130.3*255"), "A-B:C.D.E&F", "A-B:C.D.E" with F
+// defaulting to 255 and 12 hex digits (e.g. "8181c78203ff"). Groups are decimal, zero-padded ones
+// included, or hex with prefix "0x", e.g. "0x81-0x81:0xc7.0x82.3*255".
+func ParseObis(s string) (OctetString, error) {
+	if len(s) == 12 && !strings.ContainsAny(s, "-:.*&") {
+		code, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid obis code %q: %w", s, err)
+		}
+		return code, nil
+	}
+
+	groups := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '-' || r == ':' || r == '.' || r == '*' || r == '&'
+	})
+	if len(groups) == 5 && !strings.ContainsAny(s, "*&") {
+		groups = append(groups, "255")
+	}
+	if len(groups) != 6 || !validObisSeparators(s) {
+		return nil, fmt.Errorf("invalid obis code %q", s)
+	}
+
+	code := make(OctetString, 6)
+	for i, group := range groups {
+		v, err := parseObisGroup(group)
+		if err != nil {
+			return nil, fmt.Errorf("invalid obis code %q: group %q", s, group)
+		}
+		code[i] = byte(v)
+	}
+	return code, nil
+}
+
+// parseObisGroup parses a decimal OBIS group, or a hex group with prefix "0x". Leading zeros are
+// decimal, e.g. "010" is 10.
+func parseObisGroup(group string) (uint64, error) {
+	if hexGroup := strings.TrimPrefix(strings.TrimPrefix(group, "0x"), "0X"); hexGroup != group {
+		return strconv.ParseUint(hexGroup, 16, 8)
+	}
+	return strconv.ParseUint(group, 10, 8)
+}
+
+// validObisSeparators checks that groups are separated in the order "-", ":", ".", "." and "*" or "&"
+func validObisSeparators(s string) bool {
+	var seps []byte
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '-', ':', '.', '*', '&':
+			seps = append(seps, s[i])
+		}
+	}
+	switch string(seps) {
+	case "-:..*", "-:..&", "-:..":
+		return true
+	}
+	return false
+}
+
+// obisString renders 6 byte OBIS codes as "A-B:C.D.E*F" with decimal groups, other codes in hex
+func obisString(code OctetString) string {
+	if len(code) != 6 {
+		return fmt.Sprintf("%x", []byte(code))
+	}
+	return fmt.Sprintf("%d-%d:%d.%d.%d*%d", code[0], code[1], code[2], code[3], code[4], code[5])
+}
+
+// ObjectNameHex renders the entry's OBIS code with hex groups, e.g. "81-81:C7.82.03*FF" for the
+// manufacturer identification many meters send
+func (le *ListEntry) ObjectNameHex() string {
+	if len(le.ObjName) != 6 {
+		return fmt.Sprintf("%X", []byte(le.ObjName))
+	}
+	n := le.ObjName
+	return fmt.Sprintf("%02X-%02X:%02X.%02X.%02X*%02X", n[0], n[1], n[2], n[3], n[4], n[5])
+}
+
+// OBIS_WILDCARD marks a group of an ObisPattern matching any value
+const OBIS_WILDCARD = -1
+
+// ObisPattern is a prefix of OBIS groups, where each group is either a value from 0 to 255 or
+// OBIS_WILDCARD
+type ObisPattern []int
+
+// ParseObisPattern parses an OBIS pattern like "1-0:1.8.*" or "1-0:*.7.0*255". A "*" taking the place
+// of a group is a wildcard, otherwise it separates groups E and F as in ParseObis. Patterns with less
+// than 6 groups match all OBIS codes starting with them.
+func ParseObisPattern(s string) (ObisPattern, error) {
+	var groups []string
+	group, separated := "", true
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '*' && separated:
+			group = "*"
+			separated = false
+		case c == '-' || c == ':' || c == '.' || c == '&' || c == '*':
+			if separated {
+				return nil, fmt.Errorf("invalid obis pattern %q", s)
+			}
+			groups = append(groups, group)
+			group, separated = "", true
+		default:
+			group += string(c)
+			separated = false
+		}
+	}
+	if !separated {
+		groups = append(groups, group)
+	}
+	if len(groups) == 0 || len(groups) > 6 {
+		return nil, fmt.Errorf("invalid obis pattern %q", s)
+	}
+
+	pattern := make(ObisPattern, len(groups))
+	for i, group := range groups {
+		if group == "*" {
+			pattern[i] = OBIS_WILDCARD
+			continue
+		}
+		v, err := parseObisGroup(group)
+		if err != nil {
+			return nil, fmt.Errorf("invalid obis pattern %q: group %q", s, group)
+		}
+		pattern[i] = int(v)
+	}
+	return pattern, nil
+}
+
+// ObisFields decomposes an OBIS code into its groups: a (medium), b (channel), c (measured
+// quantity), d (measurement type), e (tariff) and f (billing period). ok is false for codes
+// shorter than 6 bytes.
+func (s OctetString) ObisFields() (a, b, c, d, e, f byte, ok bool) {
+	if len(s) < 6 {
+		return 0, 0, 0, 0, 0, 0, false
+	}
+	return s[0], s[1], s[2], s[3], s[4], s[5], true
+}
+
+// ObisFields decomposes the entry's OBIS code, see OctetString.ObisFields
+func (le *ListEntry) ObisFields() (a, b, c, d, e, f byte, ok bool) {
+	return le.ObjName.ObisFields()
+}
@@ -0,0 +1,299 @@
+package gosml
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Reading is a flat representation of a single list entry
+type Reading struct {
+	Obis  string
+	Value float64
+	Unit  string
+	Raw   int64
+	Time  time.Time
+}
+
+// NewReading converts a list entry to a Reading using t as its time
+func NewReading(le *ListEntry, t time.Time) Reading {
+	return Reading{
+		Obis:  le.ObjectName(),
+		Value: le.Float(),
+		Unit:  le.UnitString(),
+		Raw:   le.Value.DataInt,
+		Time:  t,
+	}
+}
+
+// ReadFrame reads from the buffered reader until the first SML file containing a GetListResponse
+// has been parsed and returns its entries as readings. Time is set to the time the file was read.
+// Unparsable files are skipped like in Read. If the reader is exhausted before such a file has
+// been found io.EOF is returned.
+func ReadFrame(r *bufio.Reader) ([]Reading, error) {
+	lists, err := readLists(r)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var readings []Reading
+	for _, list := range lists {
+		for _, elem := range list.ValList {
+			if len(elem.ObjName) >= 6 {
+				readings = append(readings, NewReading(elem, now))
+			}
+		}
+	}
+	return readings, nil
+}
+
+// readLists reads from the buffered reader until the first SML file containing a GetListResponse
+// has been parsed and returns all of its GetListResponses
+func readLists(r *bufio.Reader) ([]GetListResponse, error) {
+	for {
+		fileBytes, err := readFile(r)
+		switch {
+		case err == ErrSequenceTooLong || err == ErrUnrecognizedSequence:
+			continue
+		case err != nil:
+			return nil, err
+		}
+		messages, err := parseFrame(fileBytes)
+		if err != nil {
+			continue
+		}
+		var lists []GetListResponse
+		for _, msg := range messages {
+			if list, ok := msg.MessageBody.Data.(GetListResponse); ok {
+				lists = append(lists, list)
+			}
+		}
+		if len(lists) > 0 {
+			return lists, nil
+		}
+	}
+}
+
+// RecordStream delivers the records of ReadRecords
+type RecordStream struct {
+	C   <-chan []float64
+	err error
+}
+
+// Err returns the error that ended the stream: nil if the reader was exhausted, the context's
+// error if it was canceled and the read error otherwise. It must only be called once C is closed.
+func (s *RecordStream) Err() error {
+	return s.err
+}
+
+// ReadRecords reads SML files from the buffered reader in the background and emits one record per
+// file containing a GetListResponse. A record holds the scaled values of the entries whose OBIS codes
+// start with the codes of schema, in schema order, and NaN for codes absent in the file. C is closed
+// once the reader is exhausted or fails or ctx is canceled. A pending read isn't interrupted by
+// canceling ctx, close the underlying reader for that.
+func ReadRecords(ctx context.Context, r *bufio.Reader, schema []OctetString) (*RecordStream, error) {
+	if len(schema) == 0 {
+		return nil, errors.New("empty schema")
+	}
+	for i, code := range schema {
+		if len(code) == 0 {
+			return nil, fmt.Errorf("schema column %d: empty OBIS code", i)
+		}
+	}
+
+	records := make(chan []float64)
+	s := &RecordStream{C: records}
+	go func() {
+		defer close(records)
+		for ctx.Err() == nil {
+			lists, err := readLists(r)
+			if err != nil {
+				s.err = streamErr(err)
+				return
+			}
+			select {
+			case records <- newRecord(lists, schema):
+			case <-ctx.Done():
+			}
+		}
+		s.err = ctx.Err()
+	}()
+	return s, nil
+}
+
+// streamErr maps the error ending a stream read in the background to the error reported by Err
+func streamErr(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// newRecord maps the entries of lists to the columns of schema. The first matching entry wins.
+func newRecord(lists []GetListResponse, schema []OctetString) []float64 {
+	record := make([]float64, len(schema))
+	for i, code := range schema {
+		record[i] = math.NaN()
+	lookup:
+		for _, list := range lists {
+			for _, elem := range list.ValList {
+				if bytes.HasPrefix(elem.ObjName, code) && elem.IsNumeric() {
+					record[i] = elem.Float()
+					break lookup
+				}
+			}
+		}
+	}
+	return record
+}
+
+// ReadAverage reads the given number of SML files containing a GetListResponse from the buffered
+// reader, e.g. to smooth noisy power readings, and returns the mean scaled value per OBIS code of
+// obis. Entries are matched by prefix like in ReadRecords. Means are keyed by the codes rendered
+// like ObjectName, e.g. "1-0:1.8.0*255", codes of other lengths in hex. Codes missing from some
+// files are averaged over the files they are present in, codes missing from all files are left out.
+// Unparsable files are skipped like in Read and don't count. If the reader is exhausted before
+// enough files have been read, the error is returned.
+func ReadAverage(r *bufio.Reader, frames int, obis []OctetString) (map[string]float64, error) {
+	if frames < 1 {
+		return nil, fmt.Errorf("invalid number of frames %d", frames)
+	}
+	if len(obis) == 0 {
+		return nil, errors.New("no OBIS codes")
+	}
+	for i, code := range obis {
+		if len(code) == 0 {
+			return nil, fmt.Errorf("OBIS code %d: empty", i)
+		}
+	}
+
+	sums := make([]float64, len(obis))
+	counts := make([]int, len(obis))
+	for n := 0; n < frames; n++ {
+		lists, err := readLists(r)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", n+1, err)
+		}
+		for i, value := range newRecord(lists, obis) {
+			if !math.IsNaN(value) {
+				sums[i] += value
+				counts[i]++
+			}
+		}
+	}
+
+	means := make(map[string]float64, len(obis))
+	for i, code := range obis {
+		if counts[i] > 0 {
+			means[obisString(code)] = sums[i] / float64(counts[i])
+		}
+	}
+	return means, nil
+}
+
+// ChangeEvent reports a changed value of a register watched by Watch
+type ChangeEvent struct {
+	Obis string
+	Old  float64
+	New  float64
+	Time time.Time
+}
+
+// ChangeStream delivers the events of Watch
+type ChangeStream struct {
+	C   <-chan ChangeEvent
+	err error
+}
+
+// Err returns the error that ended the stream like RecordStream.Err. It must only be called once C
+// is closed.
+func (s *ChangeStream) Err() error {
+	return s.err
+}
+
+// Watch reads SML files from the buffered reader in the background and emits an event whenever the
+// scaled value of an entry whose OBIS code starts with one of codes differs from the one previously
+// read for the same OBIS code. The first value of each code is only remembered. Time is set to the
+// time the value was read. C is closed once the reader is exhausted or fails or ctx is canceled,
+// like in ReadRecords.
+func Watch(ctx context.Context, r *bufio.Reader, codes []OctetString) (*ChangeStream, error) {
+	if len(codes) == 0 {
+		return nil, errors.New("no OBIS codes to watch")
+	}
+
+	events := make(chan ChangeEvent)
+	s := &ChangeStream{C: events}
+	last := map[string]float64{}
+	go func() {
+		defer close(events)
+		for ctx.Err() == nil {
+			lists, err := readLists(r)
+			if err != nil {
+				s.err = streamErr(err)
+				return
+			}
+			now := time.Now()
+			for _, list := range lists {
+				for _, le := range list.ValList {
+					if !le.IsNumeric() || !hasObisPrefix(le.ObjName, codes) {
+						continue
+					}
+					key := string(le.ObjName)
+					value := le.Float()
+					old, ok := last[key]
+					last[key] = value
+					if !ok || old == value {
+						continue
+					}
+					select {
+					case events <- ChangeEvent{Obis: le.ObjectName(), Old: old, New: value, Time: now}:
+					case <-ctx.Done():
+						s.err = ctx.Err()
+						return
+					}
+				}
+			}
+		}
+		s.err = ctx.Err()
+	}()
+	return s, nil
+}
+
+// ReadOpen reads the next SML file from the buffered reader and parses only its first message, which
+// is expected to be an OpenResponse. This allows to cheaply identify the meter sending a stream.
+// Unrecognized files are skipped like in Read.
+func ReadOpen(r *bufio.Reader) (OpenResponse, error) {
+	for {
+		fileBytes, err := readFile(r)
+		switch {
+		case err == ErrSequenceTooLong || err == ErrUnrecognizedSequence:
+			continue
+		case err != nil:
+			return OpenResponse{}, err
+		}
+		return parseOpen(fileBytes[8 : len(fileBytes)-8])
+	}
+}
+
+func parseOpen(payload []byte) (open OpenResponse, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("parse panic")
+		}
+	}()
+
+	msg, err := MessageParse(&Buffer{Bytes: payload}, true)
+	if err != nil {
+		return open, err
+	}
+	open, ok := msg.MessageBody.Data.(OpenResponse)
+	if !ok {
+		return open, fmt.Errorf("unexpected message type % x (expected OpenResponse)", msg.MessageBody.Tag)
+	}
+	return open, nil
+}
@@ -0,0 +1,109 @@
+package gosml
+
+import (
+	"sync"
+)
+
+// Snapshot maps OBIS codes as returned by ListEntry.ObjectName to list entries
+type Snapshot map[string]*ListEntry
+
+// Registry keeps the latest list entry of every OBIS code. It is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	entries Snapshot
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		entries: Snapshot{},
+	}
+}
+
+// WithRegistry updates reg with every list entry read
+func WithRegistry(reg *Registry) ReadOption {
+	return WithObisCallback(OctetString{}, reg.Update)
+}
+
+// Update stores le as latest entry of its OBIS code. Entries with OBIS codes shorter than
+// 6 bytes are ignored.
+func (reg *Registry) Update(le *ListEntry) {
+	if len(le.ObjName) < 6 {
+		return
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.entries[le.ObjectName()] = le
+}
+
+// Get returns the latest entry of the given OBIS code, e.g. "1-0:1.8.0*255"
+func (reg *Registry) Get(obis string) (*ListEntry, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	le, ok := reg.entries[obis]
+	return le, ok
+}
+
+// Snapshot returns a copy of the latest entries of all OBIS codes
+func (reg *Registry) Snapshot() Snapshot {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	snapshot := make(Snapshot, len(reg.entries))
+	for obis, le := range reg.entries {
+		snapshot[obis] = le
+	}
+	return snapshot
+}
+
+// Delta returns the difference between the raw value of le and the latest stored entry of the
+// same OBIS code. ok is false if there is no previous numeric entry. If the value decreased by more
+// than threshold the register is assumed to have rolled over and the delta is computed assuming
+// wraparound at the register's width, i.e. the number of bytes its value is encoded with.
+func (reg *Registry) Delta(le *ListEntry, threshold int64) (delta int64, rollover bool, ok bool) {
+	if len(le.ObjName) < 6 || !le.IsNumeric() {
+		return 0, false, false
+	}
+	prev, found := reg.Get(le.ObjectName())
+	if !found || !prev.IsNumeric() {
+		return 0, false, false
+	}
+	return valueDelta(prev.Value, le.Value, threshold)
+}
+
+// Delta works like Registry.Delta for the entries of a snapshot
+func (s Snapshot) Delta(le *ListEntry, threshold int64) (delta int64, rollover bool, ok bool) {
+	if len(le.ObjName) < 6 || !le.IsNumeric() {
+		return 0, false, false
+	}
+	prev, found := s[le.ObjectName()]
+	if !found || !prev.IsNumeric() {
+		return 0, false, false
+	}
+	return valueDelta(prev.Value, le.Value, threshold)
+}
+
+func valueDelta(prev, cur Value, threshold int64) (delta int64, rollover bool, ok bool) {
+	delta = cur.DataInt - prev.DataInt
+	if delta >= -threshold {
+		return delta, false, true
+	}
+	width := cur.registerWidth()
+	if prevWidth := prev.registerWidth(); prevWidth > width {
+		width = prevWidth
+	}
+	return wrapDelta(prev.DataInt, cur.DataInt, width), true, true
+}
+
+// wrapDelta returns the difference of cur and prev of a register of width bytes that wrapped around
+func wrapDelta(prev, cur int64, width int) int64 {
+	mask := ^uint64(0) >> (64 - 8*uint(width))
+	return int64((uint64(cur) - uint64(prev)) & mask)
+}
+
+// registerWidth returns the number of bytes a numeric value was encoded with, e.g. 6 for a 48 bit
+// register, or its Width if it wasn't parsed
+func (v Value) registerWidth() int {
+	if n := len(v.Raw); n > 0 && n <= 8 && v.Width() > 0 {
+		return n
+	}
+	return v.Width()
+}
@@ -0,0 +1,72 @@
+package gosml
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"time"
+)
+
+type replayReader struct {
+	chunks   [][]byte
+	interval time.Duration
+	next     time.Time
+
+	// now and sleep are replaced in tests
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// NewReplayReader returns a reader that replays captured data at the given interval per SML file,
+// e.g. to simulate a meter sending one file per second. Each read returns data of a single file,
+// the first file is available immediately. Bytes between files are delivered with the following
+// file.
+func NewReplayReader(data []byte, interval time.Duration) io.Reader {
+	return &replayReader{
+		chunks:   splitFiles(data),
+		interval: interval,
+		now:      time.Now,
+		sleep:    time.Sleep,
+	}
+}
+
+// splitFiles splits data after the end of every SML file
+func splitFiles(data []byte) [][]byte {
+	var chunks [][]byte
+	src := bytes.NewReader(data)
+	r := bufio.NewReader(src)
+	start := 0
+	for {
+		_, _, err := readFileSkipped(r)
+		end := len(data) - src.Len() - r.Buffered()
+		if end > start {
+			chunks = append(chunks, data[start:end])
+			start = end
+		}
+		if err == io.EOF || end == len(data) {
+			return chunks
+		}
+	}
+}
+
+func (rr *replayReader) Read(p []byte) (int, error) {
+	if len(rr.chunks) == 0 {
+		return 0, io.EOF
+	}
+
+	if !rr.next.IsZero() {
+		if wait := rr.next.Sub(rr.now()); wait > 0 {
+			rr.sleep(wait)
+		}
+	}
+
+	n := copy(p, rr.chunks[0])
+	rr.chunks[0] = rr.chunks[0][n:]
+	if len(rr.chunks[0]) == 0 {
+		rr.chunks = rr.chunks[1:]
+		rr.next = rr.now().Add(rr.interval)
+	} else {
+		rr.next = time.Time{}
+	}
+	return n, nil
+}
@@ -0,0 +1,16 @@
+package gosml_test
+
+import (
+	"testing"
+
+	sml "github.com/petesahatt/gosml"
+	"github.com/petesahatt/gosml/smltest"
+)
+
+func TestAssertRoundTrip_MeterFixtures(t *testing.T) {
+	for name, fixture := range sml.Fixtures {
+		t.Run(name, func(t *testing.T) {
+			smltest.AssertRoundTrip(t, fixture)
+		})
+	}
+}
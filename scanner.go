@@ -0,0 +1,65 @@
+package gosml
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// FrameScanner extracts SML files from data fed in arbitrary pieces, e.g. by an interrupt driven
+// serial driver, without depending on bufio. Partial files are kept in a caller-provided buffer of
+// fixed size until they are completed by subsequent calls to Feed.
+type FrameScanner struct {
+	buf []byte
+	n   int
+}
+
+// NewFrameScanner creates a scanner keeping partial files in buf, which must hold at least 512
+// bytes, the maximum size of an SML file.
+func NewFrameScanner(buf []byte) (*FrameScanner, error) {
+	if len(buf) < maxFileSize {
+		return nil, fmt.Errorf("buffer of %d bytes too small (expected at least %d)", len(buf), maxFileSize)
+	}
+	return &FrameScanner{buf: buf}, nil
+}
+
+// Feed appends as much of p as fits into the scanner's buffer and parses all files completed by it.
+// It returns the messages of the parsed files and the number of bytes of p consumed; the remainder
+// needs to be fed again. Files that can't be parsed are dropped, the first of their errors is
+// returned along with the messages of the other files. Parsed messages don't reference the buffer.
+func (s *FrameScanner) Feed(p []byte) ([]*Message, int, error) {
+	consumed := copy(s.buf[s.n:], p)
+	s.n += consumed
+
+	var messages []*Message
+	var firstErr error
+	data := s.buf[:s.n]
+	for {
+		start := bytes.Index(data, startSeq)
+		if start < 0 {
+			// keep a partial begin sequence, discard everything else
+			data = data[len(data)-partialSuffix(data, startSeq):]
+			break
+		}
+		window := data[start+8:]
+		n, escaped, complete, err := scanRest(window)
+		if !complete {
+			data = data[start:]
+			break
+		}
+		fileBytes := data[start : start+8+n]
+		data = window[n:]
+		if err == nil {
+			var msgs []*Message
+			// copy the file as the buffer is reused
+			if msgs, err = parseFrame(unescape(append([]byte(nil), fileBytes...), escaped)); err == nil {
+				messages = append(messages, msgs...)
+			}
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.n = copy(s.buf, data)
+
+	return messages, consumed, firstErr
+}
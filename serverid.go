@@ -0,0 +1,26 @@
+package gosml
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// FormatServerID renders a server id in the display form printed on German meters' nameplates, e.g.
+// "1 EMH 00 12345678": media (1 = electricity), manufacturer FLAG id, fabrication block and serial
+// number. This requires the 10 byte layout of DIN 43863-5; other ids are rendered in hex.
+func FormatServerID(id OctetString) string {
+	if len(id) != 10 || !isFlagID(id[2:5]) {
+		return fmt.Sprintf("%x", []byte(id))
+	}
+	return fmt.Sprintf("%d %s %02d %08d", id[1], id[2:5], id[5], binary.BigEndian.Uint32(id[6:]))
+}
+
+// isFlagID reports whether b is a 3 letter manufacturer id as assigned by the FLAG association
+func isFlagID(b []byte) bool {
+	for _, c := range b {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
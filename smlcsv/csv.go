@@ -0,0 +1,46 @@
+// Package smlcsv writes SML list entries as CSV rows of the form timestamp,obis,value,unit.
+package smlcsv
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	sml "github.com/petesahatt/gosml"
+)
+
+// Writer writes list entries as CSV rows to an io.Writer
+type Writer struct {
+	w *csv.Writer
+}
+
+// NewWriter returns a Writer writing to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w: csv.NewWriter(w),
+	}
+}
+
+// WriteHeader writes the header row
+func (cw *Writer) WriteHeader() error {
+	return cw.write([]string{"timestamp", "obis", "value", "unit"})
+}
+
+// Write writes a row for the given list entry using t as timestamp
+func (cw *Writer) Write(le *sml.ListEntry, t time.Time) error {
+	return cw.write([]string{
+		t.Format(time.RFC3339),
+		le.ObjectName(),
+		strconv.FormatFloat(le.Float(), 'f', -1, 64),
+		le.UnitString(),
+	})
+}
+
+func (cw *Writer) write(record []string) error {
+	if err := cw.w.Write(record); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
@@ -0,0 +1,46 @@
+package smlcsv
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+
+	sml "github.com/petesahatt/gosml"
+	"github.com/petesahatt/gosml/smltest"
+)
+
+// GetListResponse entry of 1-0:1.8.0*255 with 1234.5 Wh
+var entryMessage = []byte{
+	0x76, 0x02, 0x01, 0x62, 0x00, 0x62, 0x00, 0x72, 0x65, 0x00, 0x00, 0x07, 0x01,
+	0x77, 0x01, 0x03, 0x01, 0x02, 0x01, 0x01, 0x71,
+	0x77, 0x07, 0x01, 0x00, 0x01, 0x08, 0x00, 0xff, 0x01, 0x01, 0x62, 0x1e, 0x52, 0xff,
+	0x65, 0x00, 0x00, 0x30, 0x39, 0x01,
+	0x01, 0x01,
+}
+
+func TestWriter(t *testing.T) {
+	var entry *sml.ListEntry
+	r := bufio.NewReader(bytes.NewReader(smltest.File(smltest.Message(entryMessage))))
+	err := sml.Read(r, sml.WithObisCallback(sml.OctetString{}, func(le *sml.ListEntry) {
+		entry = le
+	}))
+	if err != nil || entry == nil {
+		t.Fatalf("failed to read entry: %v", err)
+	}
+
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	if err := w.WriteHeader(); err != nil {
+		t.Fatalf("WriteHeader error: %v", err)
+	}
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := w.Write(entry, ts); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+
+	want := "timestamp,obis,value,unit\n2024-01-02T03:04:05Z,1-0:1.8.0*255,1234.5,Wh\n"
+	if out.String() != want {
+		t.Fatalf("got %q, want %q", out.String(), want)
+	}
+}
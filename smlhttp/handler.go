@@ -0,0 +1,48 @@
+// Package smlhttp serves the latest readings of a gosml.Registry as JSON.
+package smlhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	sml "github.com/petesahatt/gosml"
+)
+
+type handler struct {
+	reg *sml.Registry
+}
+
+// NewHTTPHandler returns a handler serving the registry's latest entries as JSON object keyed by
+// OBIS code on GET requests. The query parameter obis (e.g. ?obis=1-0:1.8.0*255) selects a single
+// entry.
+func NewHTTPHandler(reg *sml.Registry) http.Handler {
+	return &handler{reg: reg}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body interface{}
+	if obis := r.URL.Query().Get("obis"); obis != "" {
+		le, ok := h.reg.Get(obis)
+		if !ok {
+			http.Error(w, "unknown obis code", http.StatusNotFound)
+			return
+		}
+		body = le
+	} else {
+		body = h.reg.Snapshot()
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(b, '\n'))
+}
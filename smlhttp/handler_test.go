@@ -0,0 +1,88 @@
+package smlhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	sml "github.com/petesahatt/gosml"
+)
+
+func newRegistry() *sml.Registry {
+	reg := sml.NewRegistry()
+	reg.Update(&sml.ListEntry{
+		ObjName: sml.OctetString{1, 0, 1, 8, 0, 255},
+		Unit:    sml.UNIT_WATT_HOUR,
+		Value:   sml.Value{Typ: sml.OCTET_TYPE_UNSIGNED | sml.TYPE_NUMBER_32, DataInt: 12345},
+	})
+	reg.Update(&sml.ListEntry{
+		ObjName: sml.OctetString{1, 0, 16, 7, 0, 255},
+		Unit:    sml.UNIT_WATT,
+		Value:   sml.Value{Typ: sml.OCTET_TYPE_INTEGER | sml.TYPE_NUMBER_16, DataInt: 512},
+	})
+	return reg
+}
+
+func TestHandler_Snapshot(t *testing.T) {
+	h := NewHTTPHandler(newRegistry())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var body map[string]struct {
+		Obis  string
+		Value interface{}
+		Unit  string
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	entry, ok := body["1-0:1.8.0*255"]
+	if !ok || entry.Unit != "Wh" {
+		t.Fatalf("unexpected 1-0:1.8.0*255 entry %+v", entry)
+	}
+	if v, ok := entry.Value.(float64); !ok || v <= 0 {
+		t.Fatalf("unexpected value %v", entry.Value)
+	}
+}
+
+func TestHandler_SingleObis(t *testing.T) {
+	h := NewHTTPHandler(newRegistry())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?obis="+url.QueryEscape("1-0:16.7.0*255"), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var entry struct {
+		Obis string
+		Unit string
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if entry.Obis != "1-0:16.7.0*255" || entry.Unit != "W" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?obis=1-0:99.99.0*255", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status for unknown obis = %d", rec.Code)
+	}
+}
+
+func TestHandler_MethodNotAllowed(t *testing.T) {
+	h := NewHTTPHandler(sml.NewRegistry())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d", rec.Code)
+	}
+}
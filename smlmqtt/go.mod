@@ -0,0 +1,16 @@
+module github.com/petesahatt/gosml/smlmqtt
+
+go 1.19
+
+require (
+	github.com/eclipse/paho.mqtt.golang v1.4.3
+	github.com/petesahatt/gosml v0.0.0-20261015133128-c6d949230995
+)
+
+require (
+	github.com/gorilla/websocket v1.5.0 // indirect
+	golang.org/x/net v0.8.0 // indirect
+	golang.org/x/sync v0.1.0 // indirect
+)
+
+replace github.com/petesahatt/gosml => ../
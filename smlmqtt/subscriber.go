@@ -0,0 +1,26 @@
+// Package smlmqtt decodes SML files published to MQTT, e.g. by IR read heads running Tasmota.
+package smlmqtt
+
+import (
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	sml "github.com/petesahatt/gosml"
+)
+
+// Subscribe subscribes client to topic and parses every received payload with sml.ParseBytes,
+// calling the callbacks registered by opts. Payloads must contain raw SML files. As opts are applied
+// per payload, state kept by options like sml.WithDedupe doesn't carry over between payloads.
+func Subscribe(client mqtt.Client, topic string, qos byte, opts ...sml.ReadOption) error {
+	token := client.Subscribe(topic, qos, Handler(opts...))
+	token.Wait()
+	return token.Error()
+}
+
+// Handler returns a message handler parsing payloads like Subscribe, for use with
+// mqtt.ClientOptions.SetDefaultPublishHandler or custom subscriptions
+func Handler(opts ...sml.ReadOption) mqtt.MessageHandler {
+	return func(_ mqtt.Client, msg mqtt.Message) {
+		// errors of individual files are reported to the error callback, ParseBytes itself can't
+		// fail on in-memory data
+		_ = sml.ParseBytes(msg.Payload(), opts...)
+	}
+}
@@ -0,0 +1,49 @@
+package smlmqtt
+
+import (
+	"testing"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	sml "github.com/petesahatt/gosml"
+	"github.com/petesahatt/gosml/smltest"
+)
+
+// GetListResponse entry of 1-0:1.8.0*255 with 1234.5 Wh
+var entryMessage = []byte{
+	0x76, 0x02, 0x01, 0x62, 0x00, 0x62, 0x00, 0x72, 0x65, 0x00, 0x00, 0x07, 0x01,
+	0x77, 0x01, 0x03, 0x01, 0x02, 0x01, 0x01, 0x71,
+	0x77, 0x07, 0x01, 0x00, 0x01, 0x08, 0x00, 0xff, 0x01, 0x01, 0x62, 0x1e, 0x52, 0xff,
+	0x65, 0x00, 0x00, 0x30, 0x39, 0x01,
+	0x01, 0x01,
+}
+
+type message struct {
+	mqtt.Message
+	payload []byte
+}
+
+func (m message) Payload() []byte {
+	return m.payload
+}
+
+func TestHandler(t *testing.T) {
+	var values []float64
+	var errs []error
+	handler := Handler(
+		sml.WithObisCallback(sml.OctetString{1, 0, 1, 8, 0}, func(le *sml.ListEntry) {
+			values = append(values, le.Float())
+		}),
+		sml.WithErrorCallback(func(err error) { errs = append(errs, err) }))
+
+	f := smltest.File(smltest.Message(entryMessage))
+	handler(nil, message{payload: f})
+	handler(nil, message{payload: append(append([]byte(nil), f...), f...)})
+	handler(nil, message{payload: []byte("not sml")})
+
+	if len(values) != 3 || values[0] != 1234.5 {
+		t.Fatalf("got values %v, want 3 times 1234.5", values)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("got errors %v, want one for the skipped bytes", errs)
+	}
+}
@@ -0,0 +1,64 @@
+// Package smlotel reports the latest readings of a gosml.Registry as OpenTelemetry metrics.
+package smlotel
+
+import (
+	"context"
+
+	sml "github.com/petesahatt/gosml"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Collector observes the numeric entries of a registry on each collection cycle
+type Collector struct {
+	reg          *sml.Registry
+	gauge        metric.Float64ObservableGauge
+	registration metric.Registration
+}
+
+// NewCollector registers an observable gauge named "sml.value" with meter that reports Float() of
+// every numeric entry of reg with the attributes obis and unit. OBIS codes are discovered as they
+// appear in the registry.
+func NewCollector(meter metric.Meter, reg *sml.Registry) (*Collector, error) {
+	gauge, err := meter.Float64ObservableGauge("sml.value",
+		metric.WithDescription("Latest value of an SML list entry"))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Collector{reg: reg, gauge: gauge}
+	c.registration, err = meter.RegisterCallback(c.observe, gauge)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Collector) observe(_ context.Context, o metric.Observer) error {
+	for _, le := range c.reg.Snapshot() {
+		Observe(o, c.gauge, le)
+	}
+	return nil
+}
+
+// Close unregisters the collector's callback
+func (c *Collector) Close() error {
+	return c.registration.Unregister()
+}
+
+// Observe reports the value of a numeric list entry for gauge with the attributes obis and unit.
+// Entries with non-numeric values are ignored.
+func Observe(o metric.Observer, gauge metric.Float64Observable, le *sml.ListEntry) {
+	if !le.IsNumeric() {
+		return
+	}
+	o.ObserveFloat64(gauge, le.Float(), metric.WithAttributes(Attributes(le)...))
+}
+
+// Attributes returns the attributes identifying a list entry: obis (e.g. "1-0:1.8.0*255") and unit
+func Attributes(le *sml.ListEntry) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("obis", le.ObjectName()),
+		attribute.String("unit", le.UnitString()),
+	}
+}
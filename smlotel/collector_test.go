@@ -0,0 +1,67 @@
+package smlotel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"testing"
+
+	sml "github.com/petesahatt/gosml"
+	"github.com/petesahatt/gosml/smltest"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// GetListResponse entries of 1-0:1.8.0*255 with 1234.5 Wh and 1-0:96.50.1*1 with "EMH"
+var entryMessage = []byte{
+	0x76, 0x02, 0x01, 0x62, 0x00, 0x62, 0x00, 0x72, 0x65, 0x00, 0x00, 0x07, 0x01,
+	0x77, 0x01, 0x03, 0x01, 0x02, 0x01, 0x01, 0x72,
+	0x77, 0x07, 0x01, 0x00, 0x01, 0x08, 0x00, 0xff, 0x01, 0x01, 0x62, 0x1e, 0x52, 0xff,
+	0x65, 0x00, 0x00, 0x30, 0x39, 0x01,
+	0x77, 0x07, 0x01, 0x00, 0x60, 0x32, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01,
+	0x04, 0x45, 0x4d, 0x48, 0x01,
+	0x01, 0x01,
+}
+
+func TestCollector(t *testing.T) {
+	reg := sml.NewRegistry()
+	if err := sml.Read(bufio.NewReader(bytes.NewReader(smltest.File(smltest.Message(entryMessage)))), sml.WithRegistry(reg)); err != nil {
+		t.Fatal(err)
+	}
+	if len(reg.Snapshot()) != 2 {
+		t.Fatalf("registry holds %d entries, want 2", len(reg.Snapshot()))
+	}
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	c, err := NewCollector(provider.Meter("test"), reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	if len(rm.ScopeMetrics) != 1 || len(rm.ScopeMetrics[0].Metrics) != 1 {
+		t.Fatalf("unexpected metrics %+v", rm)
+	}
+	m := rm.ScopeMetrics[0].Metrics[0]
+	gauge, ok := m.Data.(metricdata.Gauge[float64])
+	if m.Name != "sml.value" || !ok {
+		t.Fatalf("unexpected metric %s %T", m.Name, m.Data)
+	}
+	if len(gauge.DataPoints) != 1 {
+		t.Fatalf("got %d data points, want 1 (non-numeric entries are skipped)", len(gauge.DataPoints))
+	}
+	dp := gauge.DataPoints[0]
+	if dp.Value != 1234.5 {
+		t.Errorf("value = %v, want 1234.5", dp.Value)
+	}
+	want := attribute.NewSet(attribute.String("obis", "1-0:1.8.0*255"), attribute.String("unit", "Wh"))
+	if !dp.Attributes.Equals(&want) {
+		t.Errorf("attributes = %v, want %v", dp.Attributes, want)
+	}
+}
@@ -0,0 +1,39 @@
+package smltest
+
+// File wraps payload, one or more complete SML messages, into an SML file with begin and end
+// sequence, padding and a valid file CRC. Escape sequences within payload aren't escaped.
+func File(payload []byte) []byte {
+	b := []byte{0x1b, 0x1b, 0x1b, 0x1b, 0x01, 0x01, 0x01, 0x01}
+	b = append(b, payload...)
+	padding := (4 - len(payload)%4) % 4
+	for i := 0; i < padding; i++ {
+		b = append(b, 0x00)
+	}
+	b = append(b, 0x1b, 0x1b, 0x1b, 0x1b, 0x1a, byte(padding))
+	crc := crc16(b)
+	return append(b, byte(crc>>8), byte(crc))
+}
+
+// Message completes msg, an SML message list without CRC, with its CRC and end of message byte
+func Message(msg []byte) []byte {
+	crc := crc16(msg)
+	out := append([]byte(nil), msg...)
+	return append(out, 0x63, byte(crc>>8), byte(crc), 0x00)
+}
+
+// crc16 calculates the CRC-16/X-25 of b, byte-swapped like in SML files
+func crc16(b []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, c := range b {
+		crc ^= uint16(c)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = (crc >> 1) ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	crc ^= 0xffff
+	return crc<<8 | crc>>8
+}
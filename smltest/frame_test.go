@@ -0,0 +1,32 @@
+package smltest
+
+import (
+	"bytes"
+	"testing"
+
+	sml "github.com/petesahatt/gosml"
+)
+
+func TestFile(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 4} {
+		msg := &sml.Message{
+			TransactionID: bytes.Repeat([]byte{0x42}, n),
+			MessageBody: sml.MessageBody{
+				Tag:  sml.MESSAGE_OPEN_REQUEST,
+				Data: sml.OpenRequest{ClientID: sml.OctetString{0x01}, ReqFileID: sml.OctetString{0x02}},
+			},
+		}
+		encoded, err := sml.EncodeMessage(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := sml.EncodeFile(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// strip CRC and end of message byte
+		if got := File(Message(encoded[:len(encoded)-4])); !bytes.Equal(got, want) {
+			t.Errorf("File() = % x, want % x", got, want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Package smltest provides helpers for testing code that reads SML from a meter.
+package smltest
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// NewMockMeter returns a connection serving the given SML files once in order, like a meter
+// connected via a TCP IR head. The connection reaches EOF after the last file. The returned func
+// stops the meter and closes the connection.
+func NewMockMeter(frames [][]byte) (net.Conn, func()) {
+	return NewMockMeterInterval(frames, 0)
+}
+
+// NewMockMeterInterval works like NewMockMeter but waits interval before each file, mimicking a
+// meter's sending cadence
+func NewMockMeterInterval(frames [][]byte, interval time.Duration) (net.Conn, func()) {
+	if interval <= 0 {
+		return newMockMeter(frames, nil)
+	}
+	return newMockMeter(frames, func() <-chan time.Time { return time.After(interval) })
+}
+
+// newMockMeter serves frames, waiting for a tick of the channel returned by wait before each file
+// if wait is set
+func newMockMeter(frames [][]byte, wait func() <-chan time.Time) (net.Conn, func()) {
+	client, server := net.Pipe()
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer server.Close()
+		for _, frame := range frames {
+			if wait != nil {
+				select {
+				case <-wait():
+				case <-done:
+					return
+				}
+			}
+			if _, err := server.Write(frame); err != nil {
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			client.Close()
+			wg.Wait()
+		})
+	}
+	return client, stop
+}
@@ -0,0 +1,71 @@
+package smltest
+
+import (
+	"bufio"
+	"testing"
+	"time"
+
+	sml "github.com/petesahatt/gosml"
+)
+
+func testFrames(t *testing.T, n int) [][]byte {
+	var frames [][]byte
+	for i := 0; i < n; i++ {
+		frame, err := sml.EncodeFile(&sml.Message{
+			TransactionID: sml.OctetString{byte(i)},
+			MessageBody: sml.MessageBody{
+				Tag:  sml.MESSAGE_OPEN_REQUEST,
+				Data: sml.OpenRequest{ClientID: sml.OctetString{0x01}, ReqFileID: sml.OctetString{byte(i)}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func TestMockMeter(t *testing.T) {
+	conn, stop := NewMockMeter(testFrames(t, 3))
+	defer stop()
+
+	var n int
+	err := sml.Read(bufio.NewReader(conn), sml.WithRawFrameCallback(func([]byte) { n++ }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("read %d files, want 3", n)
+	}
+}
+
+func TestMockMeterInterval(t *testing.T) {
+	tick := make(chan time.Time)
+	conn, stop := newMockMeter(testFrames(t, 3), func() <-chan time.Time { return tick })
+	defer stop()
+
+	files := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- sml.Read(bufio.NewReader(conn), sml.WithRawFrameCallback(func([]byte) { files <- struct{}{} }))
+	}()
+
+	for i := 0; i < 3; i++ {
+		// the send only succeeds while the meter waits, i.e. before it wrote the next file
+		tick <- time.Time{}
+		<-files
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMockMeterStop(t *testing.T) {
+	conn, stop := NewMockMeterInterval(testFrames(t, 3), time.Hour)
+	stop()
+	stop()
+	if err := sml.Read(bufio.NewReader(conn)); err == nil {
+		t.Fatal("expected error reading from stopped meter")
+	}
+}
@@ -0,0 +1,189 @@
+package smltest
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	sml "github.com/petesahatt/gosml"
+)
+
+// AssertRoundTrip parses the SML files contained in frame, re-encodes their messages with
+// sml.EncodeMessage and fails t unless the result matches the original files in canonical form.
+// The canonical form accounts for legal encoding differences: numbers are reduced to their minimal
+// width, message CRCs (which change along with the encoding) and padding are dropped. Beyond that,
+// the normalizations documented for sml.EncodeMessage show up as differences.
+func AssertRoundTrip(t testing.TB, frame []byte) {
+	t.Helper()
+
+	var payloads [][]byte
+	err := sml.ParseBytes(frame, sml.WithRawFrameCallback(func(payload []byte) {
+		payloads = append(payloads, append([]byte(nil), payload...))
+	}), sml.WithErrorCallback(func(err error) {
+		t.Errorf("parsing: %v", err)
+	}))
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	if len(payloads) == 0 {
+		t.Fatal("no SML file found")
+	}
+
+	for i, payload := range payloads {
+		messages, err := sml.ParseMessages(payload)
+		if err != nil {
+			t.Errorf("file %d: %v", i, err)
+			continue
+		}
+		var encoded []byte
+		for _, msg := range messages {
+			msgBytes, err := sml.EncodeMessage(msg)
+			if err != nil {
+				t.Errorf("file %d: encoding message % x: %v", i, msg.MessageBody.Tag, err)
+				return
+			}
+			encoded = append(encoded, msgBytes...)
+		}
+
+		want, err := canonical(payload)
+		if err != nil {
+			t.Errorf("file %d: original: %v", i, err)
+			continue
+		}
+		got, err := canonical(encoded)
+		if err != nil {
+			t.Errorf("file %d: encoded: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("file %d: round trip differs in canonical form\noriginal: % x\nencoded:  % x", i, want, got)
+		}
+	}
+}
+
+const (
+	typeField     = 0x70
+	typeInteger   = 0x50
+	typeUnsigned  = 0x60
+	typeList      = 0x70
+	anotherTL     = 0x80
+	lengthField   = 0x0f
+	messageFields = 6
+	messageCrc    = 4
+)
+
+// canonical returns the canonical form of the messages of an SML file payload
+func canonical(payload []byte) ([]byte, error) {
+	var out []byte
+	for pos := 0; pos < len(payload); {
+		if payload[pos] == 0x00 {
+			// end of message or padding
+			pos++
+			continue
+		}
+		var err error
+		if out, pos, err = canonicalElement(out, payload, pos, true); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// canonicalElement appends the canonical form of the element at pos and returns the position of
+// the following element. message is set for the top-level list of a message, whose CRC is dropped.
+func canonicalElement(out, data []byte, pos int, message bool) ([]byte, int, error) {
+	typ, length, tlLen, err := readTL(data, pos)
+	if err != nil {
+		return nil, 0, err
+	}
+	pos += tlLen
+
+	if typ == typeList {
+		out = appendTL(out, typ, length)
+		for i := 0; i < length; i++ {
+			if message && length == messageFields && i == messageCrc {
+				_, crcLen, crcTL, err := readTL(data, pos)
+				if err != nil {
+					return nil, 0, err
+				}
+				pos += crcTL + crcLen
+				continue
+			}
+			if out, pos, err = canonicalElement(out, data, pos, false); err != nil {
+				return nil, 0, err
+			}
+		}
+		return out, pos, nil
+	}
+
+	if length < 0 {
+		// end of message
+		return append(out, 0x00), pos, nil
+	}
+	if pos+length > len(data) {
+		return nil, 0, fmt.Errorf("element of %d bytes exceeds data at offset %d", length, pos)
+	}
+	value := data[pos : pos+length]
+	switch typ {
+	case typeUnsigned:
+		for len(value) > 1 && value[0] == 0x00 {
+			value = value[1:]
+		}
+	case typeInteger:
+		for len(value) > 1 && ((value[0] == 0x00 && value[1]&0x80 == 0) || (value[0] == 0xff && value[1]&0x80 != 0)) {
+			value = value[1:]
+		}
+	}
+	out = appendTL(out, typ, len(value))
+	return append(out, value...), pos + length, nil
+}
+
+// readTL reads the type-length field at pos. For lists length is the number of elements, for other
+// types the number of data bytes.
+func readTL(data []byte, pos int) (typ uint8, length int, tlLen int, err error) {
+	if pos >= len(data) {
+		return 0, 0, 0, fmt.Errorf("unexpected end of data at offset %d", pos)
+	}
+	typ = data[pos] & typeField
+	for {
+		if pos+tlLen >= len(data) {
+			return 0, 0, 0, fmt.Errorf("unexpected end of data at offset %d", pos+tlLen)
+		}
+		b := data[pos+tlLen]
+		length = length<<4 | int(b&lengthField)
+		tlLen++
+		if b&anotherTL == 0 {
+			break
+		}
+	}
+	if typ != typeList {
+		length -= tlLen
+	}
+	return typ, length, tlLen, nil
+}
+
+// appendTL appends a type-length field with the minimal number of TL bytes
+func appendTL(b []byte, typ uint8, length int) []byte {
+	tlLen := 1
+	if typ == typeList {
+		for length >= 1<<(4*tlLen) {
+			tlLen++
+		}
+	} else {
+		for length+tlLen >= 1<<(4*tlLen) {
+			tlLen++
+		}
+		length += tlLen
+	}
+	for i := tlLen - 1; i >= 0; i-- {
+		tl := uint8(length>>(4*i)) & lengthField
+		if i == tlLen-1 {
+			tl |= typ
+		}
+		if i > 0 {
+			tl |= anotherTL
+		}
+		b = append(b, tl)
+	}
+	return b
+}
@@ -0,0 +1,47 @@
+package smltest
+
+import (
+	"bytes"
+	"testing"
+)
+
+var (
+	openResponse = Message([]byte{0x76, 0x02, 0x01, 0x62, 0x00, 0x62, 0x00, 0x72, 0x63, 0x01, 0x01,
+		0x76, 0x01, 0x01, 0x02, 0x31, 0x0b, 0x0a, 0x01, 0x44, 0x5a, 0x47, 0x00, 0x02, 0x82, 0x22, 0x5e,
+		0x72, 0x62, 0x01, 0x65, 0x05, 0xe7, 0x48, 0xd7, 0x62, 0x02})
+	getListResponse = Message([]byte{0x76, 0x02, 0x02, 0x62, 0x00, 0x62, 0x00, 0x72, 0x63, 0x07, 0x01,
+		0x77, 0x01, 0x0b, 0x0a, 0x01, 0x44, 0x5a, 0x47, 0x00, 0x02, 0x82, 0x22, 0x5e,
+		0x07, 0x01, 0x00, 0x62, 0x0a, 0xff, 0xff, 0x72, 0x62, 0x01, 0x65, 0x05, 0xe7, 0x48, 0xd7,
+		0x73,
+		// manufacturer as octet string
+		0x77, 0x07, 0x01, 0x00, 0x60, 0x32, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x04, 0x44, 0x5a, 0x47, 0x01,
+		// 1.8.0 with status, valTime and a 32 bit value
+		0x77, 0x07, 0x01, 0x00, 0x01, 0x08, 0x00, 0xff, 0x65, 0x00, 0x1c, 0x01, 0x04,
+		0x72, 0x62, 0x01, 0x65, 0x05, 0xe7, 0x48, 0xd7, 0x62, 0x1e, 0x52, 0xff, 0x65, 0x03, 0x3c, 0x93, 0x89, 0x01,
+		// 16.7.0 with a negative 16 bit value
+		0x77, 0x07, 0x01, 0x00, 0x10, 0x07, 0x00, 0xff, 0x01, 0x01, 0x62, 0x1b, 0x52, 0xfe, 0x53, 0x8b, 0x28, 0x01,
+		0x01, 0x01})
+	signedListResponse = Message([]byte{0x76, 0x02, 0x01, 0x62, 0x00, 0x62, 0x00, 0x72, 0x63, 0x07, 0x01,
+		0x77, 0x01, 0x03, 0x01, 0x02, 0x01, 0x01, 0x71,
+		// 1.8.0 with a 64 bit value and a signature
+		0x77, 0x07, 0x01, 0x00, 0x01, 0x08, 0x00, 0xff, 0x01, 0x01, 0x62, 0x1e, 0x52, 0x00,
+		0x69, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0xe2, 0x40, 0x05, 0xaa, 0xbb, 0xcc, 0xdd,
+		0x03, 0x11, 0x22, 0x01})
+	closeResponse = Message([]byte{0x76, 0x02, 0x03, 0x62, 0x00, 0x62, 0x00, 0x72, 0x63, 0x02, 0x01, 0x71, 0x01})
+)
+
+// fixtures are SML files shaped like the ones of real meters, with numbers wider than necessary as
+// many meters send them
+var fixtures = map[string][]byte{
+	"session": File(bytes.Join([][]byte{openResponse, getListResponse, closeResponse}, nil)),
+	"signed":  File(signedListResponse),
+	"several": append(File(getListResponse), File(signedListResponse)...),
+}
+
+func TestAssertRoundTrip_Fixtures(t *testing.T) {
+	for name, data := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			AssertRoundTrip(t, data)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package gosml
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeKind is the choice tag of an SML time
+type TimeKind uint8
+
+const (
+	TIME_KIND_NONE            TimeKind = 0x00 // time was skipped
+	TIME_KIND_SEC_INDEX       TimeKind = 0x01 // seconds since an arbitrary meter specific point in time
+	TIME_KIND_TIMESTAMP       TimeKind = 0x02 // seconds since 1970-01-01 UTC
+	TIME_KIND_LOCAL_TIMESTAMP TimeKind = 0x03 // timestamp with local and season time offsets
+	TIME_KIND_UNKNOWN         TimeKind = 0xff // time with a choice tag not defined by the spec, skipped
+)
+
+// known reports whether k is one of the time kinds defined by the spec
+func (k TimeKind) known() bool {
+	return k >= TIME_KIND_SEC_INDEX && k <= TIME_KIND_LOCAL_TIMESTAMP
+}
+
+// ErrUnknownTimeKind means that a time couldn't be parsed as its choice tag or encoding is unknown
+var ErrUnknownTimeKind = errors.New("unknown time kind")
+
+// TimeChoiceParse parses an SML time and returns its value and kind. For local timestamps offset is
+// the sum of the local and season time offsets in minutes. Times with unknown choice tags are
+// skipped and returned as zero with TIME_KIND_UNKNOWN, see WithSanityCheck to reject them.
+func (buf *Buffer) TimeChoiceParse() (timestamp Time, kind TimeKind, offset int, err error) {
+	buf.Debug()
+
+	if skip := buf.OptionalIsSkipped(); skip {
+		return 0, TIME_KIND_NONE, 0, nil
+	}
+
+	if err := buf.Expect(OCTET_TYPE_LIST, 2); err != nil {
+		return 0, TIME_KIND_NONE, 0, err
+	}
+
+	tag, err := buf.U8Parse()
+	if err != nil {
+		return 0, TIME_KIND_NONE, 0, err
+	}
+	kind = TimeKind(tag)
+
+	if !kind.known() {
+		// unknown choice, e.g. a vendor extension or tag 0: skip the time so that the rest of the
+		// message can still be parsed. Callers see TIME_KIND_UNKNOWN and no time.
+		if err := buf.skipElement(); err != nil {
+			return 0, TIME_KIND_UNKNOWN, 0, fmt.Errorf("%w %d: %v", ErrUnknownTimeKind, tag, err)
+		}
+		return 0, TIME_KIND_UNKNOWN, 0, nil
+	}
+
+	var value uint32
+
+	typeField := buf.GetNextType()
+	switch typeField {
+	case OCTET_TYPE_UNSIGNED:
+		if value, err = buf.U32Parse(); err != nil {
+			return 0, kind, 0, err
+		}
+	case OCTET_TYPE_LIST:
+		// localTimestamp: timestamp, localOffset and seasonTimeOffset
+		// (e.g. FROETEC Multiflex ZG22)
+		buf.GetNextLength() // should we check the length here?
+
+		if value, err = buf.U32Parse(); err != nil {
+			return 0, kind, 0, err
+		}
+		localOffset, err := buf.I16Parse()
+		if err != nil {
+			return 0, kind, 0, err
+		}
+		seasonOffset, err := buf.I16Parse()
+		if err != nil {
+			return 0, kind, 0, err
+		}
+		offset = int(localOffset) + int(seasonOffset)
+	default:
+		return 0, kind, 0, fmt.Errorf("%w: invalid time format %02x", ErrUnknownTimeKind, typeField)
+	}
+
+	return Time(value), kind, offset, nil
+}
+
+// wallClock converts timestamps and local timestamps to time.Time in loc. Without loc timestamps are
+// returned in UTC and local timestamps in the zone given by their offset. It returns the zero time
+// for other kinds.
+func wallClock(t Time, kind TimeKind, offset int, loc *time.Location) time.Time {
+	var wall time.Time
+	switch kind {
+	case TIME_KIND_TIMESTAMP:
+		wall = time.Unix(int64(t), 0).UTC()
+	case TIME_KIND_LOCAL_TIMESTAMP:
+		wall = time.Unix(int64(t), 0).In(time.FixedZone("", offset*60))
+	default:
+		return time.Time{}
+	}
+	if loc != nil {
+		wall = wall.In(loc)
+	}
+	return wall
+}
+
+// SensorTime returns ActSensorTime as time.Time along with its kind. The time is only set for
+// (local) timestamps, sec indexes are relative to a meter specific point in time and can't be
+// converted. The time is presented in the zone set by WithTimezone.
+func (list *GetListResponse) SensorTime() (time.Time, TimeKind) {
+	return wallClock(list.ActSensorTime, list.actSensorTimeKind, list.actSensorTimeOffset, list.location), list.actSensorTimeKind
+}
+
+// ValTime returns the time the entry's value refers to, e.g. the capture time of a maximum demand,
+// along with its kind. Like in SensorTime the time is only set for (local) timestamps.
+func (le *ListEntry) ValTime() (time.Time, TimeKind) {
+	return wallClock(le.valTime, le.valTimeKind, le.valTimeOffset, le.location), le.valTimeKind
+}
+
+// GroupByValTime groups the entries of the list by their valTime, e.g. for meters batching values
+// captured at different instants into one list. Entries without valTime or with one of unknown kind
+// are grouped under ActSensorTime. Within a group entries keep their order. Like the raw times, keys
+// of different time kinds aren't comparable.
+func (list *GetListResponse) GroupByValTime() map[Time][]*ListEntry {
+	groups := map[Time][]*ListEntry{}
+	for _, elem := range list.ValList {
+		key := elem.valTime
+		if !elem.valTimeKind.known() {
+			key = list.ActSensorTime
+		}
+		groups[key] = append(groups[key], elem)
+	}
+	return groups
+}
@@ -0,0 +1,285 @@
+package gosml
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	PROC_PAR_VALUE_TAG_VALUE        = 0x01
+	PROC_PAR_VALUE_TAG_PERIOD_ENTRY = 0x02
+	PROC_PAR_VALUE_TAG_TUPEL_ENTRY  = 0x03
+	PROC_PAR_VALUE_TAG_TIME         = 0x04
+)
+
+type TreePath []OctetString
+
+type Tree struct {
+	ParameterName  OctetString
+	ParameterValue *ProcParValue // optional
+	ChildList      []*Tree       // optional
+}
+
+type ProcParValue struct {
+	Tag         uint8
+	Value       Value
+	PeriodEntry *PeriodEntry
+	TupelEntry  *TupelEntry
+	Time        Time
+}
+
+type PeriodEntry struct {
+	ObjName        OctetString
+	Unit           uint8
+	scaler         int8
+	Value          Value
+	ValueSignature OctetString
+}
+
+func (pe *PeriodEntry) Scaler() float64 {
+	return math.Pow10(int(pe.scaler))
+}
+
+func (pe *PeriodEntry) isNumeric() bool {
+	typ := pe.Value.Typ & OCTET_TYPE_FIELD
+	return typ == OCTET_TYPE_INTEGER || typ == OCTET_TYPE_UNSIGNED
+}
+
+// what a messy tupel ...
+type TupelEntry struct {
+	ServerID OctetString
+	SecIndex Time
+	Status   uint64
+
+	UnitPA   uint8
+	ScalerPA int8
+	ValuePA  int64
+
+	UnitR1   uint8
+	ScalerR1 int8
+	ValueR1  int64
+
+	UnitR4          uint8
+	ScalerR4        int8
+	ValueR4         int64
+	SignaturePAR1R4 OctetString
+
+	UnitMA   uint8
+	ScalerMA int8
+	ValueMA  int64
+
+	UnitR2   uint8
+	ScalerR2 int8
+	ValueR2  int64
+
+	UnitR3          uint8
+	ScalerR3        int8
+	ValueR3         int64
+	SignatureMAR2R3 OctetString
+}
+
+func TreePathParse(buf *Buffer) (TreePath, error) {
+	if buf.OptionalIsSkipped() {
+		return nil, nil
+	}
+
+	if err := buf.ExpectType(OCTET_TYPE_LIST); err != nil {
+		return nil, err
+	}
+
+	path := TreePath{}
+
+	for elems := buf.GetNextLength(); elems > 0; elems-- {
+		entry, err := buf.OctetStringParse()
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			path = append(path, entry)
+		}
+	}
+
+	return path, nil
+}
+
+func TreeParse(buf *Buffer) (*Tree, error) {
+	if buf.OptionalIsSkipped() {
+		return nil, nil
+	}
+
+	tree := &Tree{}
+	var err error
+
+	if err := buf.Expect(OCTET_TYPE_LIST, 3); err != nil {
+		return nil, err
+	}
+
+	if tree.ParameterName, err = buf.OctetStringParse(); err != nil {
+		return nil, err
+	}
+
+	if tree.ParameterValue, err = ProcParValueParse(buf); err != nil {
+		return nil, err
+	}
+
+	if !buf.OptionalIsSkipped() {
+		if err := buf.ExpectType(OCTET_TYPE_LIST); err != nil {
+			return nil, err
+		}
+
+		for elems := buf.GetNextLength(); elems > 0; elems-- {
+			child, err := TreeParse(buf)
+			if err != nil {
+				return nil, err
+			}
+			if child != nil {
+				tree.ChildList = append(tree.ChildList, child)
+			}
+		}
+	}
+
+	return tree, nil
+}
+
+func ProcParValueParse(buf *Buffer) (*ProcParValue, error) {
+	if buf.OptionalIsSkipped() {
+		return nil, nil
+	}
+
+	ppv := &ProcParValue{}
+	var err error
+
+	if err := buf.Expect(OCTET_TYPE_LIST, 2); err != nil {
+		return nil, err
+	}
+
+	if ppv.Tag, err = buf.U8Parse(); err != nil {
+		return nil, err
+	}
+
+	switch ppv.Tag {
+	case PROC_PAR_VALUE_TAG_VALUE:
+		ppv.Value, err = buf.ValueParse()
+	case PROC_PAR_VALUE_TAG_PERIOD_ENTRY:
+		ppv.PeriodEntry, err = PeriodEntryParse(buf)
+	case PROC_PAR_VALUE_TAG_TUPEL_ENTRY:
+		ppv.TupelEntry, err = TupelEntryParse(buf)
+	case PROC_PAR_VALUE_TAG_TIME:
+		ppv.Time, err = buf.TimeParse()
+	default:
+		return nil, fmt.Errorf("invalid proc par value tag %02x", ppv.Tag)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ppv, nil
+}
+
+func PeriodEntryParse(buf *Buffer) (*PeriodEntry, error) {
+	if buf.OptionalIsSkipped() {
+		return nil, nil
+	}
+
+	period := &PeriodEntry{}
+	var err error
+
+	if err := buf.Expect(OCTET_TYPE_LIST, 5); err != nil {
+		return nil, err
+	}
+
+	if period.ObjName, err = buf.OctetStringParse(); err != nil {
+		return nil, err
+	}
+
+	if period.Unit, err = buf.U8Parse(); err != nil {
+		return nil, err
+	}
+
+	if period.scaler, err = buf.I8Parse(); err != nil {
+		return nil, err
+	}
+
+	if period.Value, err = buf.ValueParse(); err != nil {
+		return nil, err
+	}
+
+	if period.ValueSignature, err = buf.OctetStringParse(); err != nil {
+		return nil, err
+	}
+
+	return period, nil
+}
+
+func TupelEntryParse(buf *Buffer) (*TupelEntry, error) {
+	if buf.OptionalIsSkipped() {
+		return nil, nil
+	}
+
+	tupel := &TupelEntry{}
+	var err error
+
+	if err := buf.Expect(OCTET_TYPE_LIST, 23); err != nil {
+		return nil, err
+	}
+
+	if tupel.ServerID, err = buf.OctetStringParse(); err != nil {
+		return nil, err
+	}
+
+	if tupel.SecIndex, err = buf.TimeParse(); err != nil {
+		return nil, err
+	}
+
+	if tupel.Status, err = buf.U64Parse(); err != nil {
+		return nil, err
+	}
+
+	if tupel.UnitPA, tupel.ScalerPA, tupel.ValuePA, err = buf.tupelValueParse(); err != nil {
+		return nil, err
+	}
+
+	if tupel.UnitR1, tupel.ScalerR1, tupel.ValueR1, err = buf.tupelValueParse(); err != nil {
+		return nil, err
+	}
+
+	if tupel.UnitR4, tupel.ScalerR4, tupel.ValueR4, err = buf.tupelValueParse(); err != nil {
+		return nil, err
+	}
+
+	if tupel.SignaturePAR1R4, err = buf.OctetStringParse(); err != nil {
+		return nil, err
+	}
+
+	if tupel.UnitMA, tupel.ScalerMA, tupel.ValueMA, err = buf.tupelValueParse(); err != nil {
+		return nil, err
+	}
+
+	if tupel.UnitR2, tupel.ScalerR2, tupel.ValueR2, err = buf.tupelValueParse(); err != nil {
+		return nil, err
+	}
+
+	if tupel.UnitR3, tupel.ScalerR3, tupel.ValueR3, err = buf.tupelValueParse(); err != nil {
+		return nil, err
+	}
+
+	if tupel.SignatureMAR2R3, err = buf.OctetStringParse(); err != nil {
+		return nil, err
+	}
+
+	return tupel, nil
+}
+
+// tupelValueParse parses the unit, scaler and value triple used in tupel entries
+func (buf *Buffer) tupelValueParse() (unit uint8, scaler int8, value int64, err error) {
+	if unit, err = buf.U8Parse(); err != nil {
+		return
+	}
+
+	if scaler, err = buf.I8Parse(); err != nil {
+		return
+	}
+
+	value, err = buf.I64Parse()
+	return
+}
@@ -0,0 +1,111 @@
+package gosml
+
+// Unit codes as defined in DLMS/COSEM (IEC 62056-62), used by SML list entries
+const (
+	UNIT_YEAR          = 1
+	UNIT_MONTH         = 2
+	UNIT_WEEK          = 3
+	UNIT_DAY           = 4
+	UNIT_HOUR          = 5
+	UNIT_MINUTE        = 6
+	UNIT_SECOND        = 7
+	UNIT_DEGREE        = 8
+	UNIT_DEGREE_C      = 9
+	UNIT_CURRENCY      = 10
+	UNIT_METRE         = 11
+	UNIT_METRE_PER_SEC = 12
+	UNIT_CUBIC_METRE   = 13
+	UNIT_LITRE         = 19
+	UNIT_KILOGRAM      = 20
+	UNIT_NEWTON        = 21
+	UNIT_PASCAL        = 23
+	UNIT_BAR           = 24
+	UNIT_JOULE         = 25
+	UNIT_WATT          = 27
+	UNIT_VOLT_AMPERE   = 28
+	UNIT_VAR           = 29
+	UNIT_WATT_HOUR     = 30
+	UNIT_VA_HOUR       = 31
+	UNIT_VAR_HOUR      = 32
+	UNIT_AMPERE        = 33
+	UNIT_COULOMB       = 34
+	UNIT_VOLT          = 35
+	UNIT_OHM           = 38
+	UNIT_HERTZ         = 44
+	UNIT_KELVIN        = 52
+	UNIT_PERCENT       = 56
+	UNIT_AMPERE_HOUR   = 57
+	UNIT_OTHER         = 254
+	UNIT_COUNT         = 255
+)
+
+var unitNames = map[uint8]string{
+	UNIT_YEAR:          "a",
+	UNIT_MONTH:         "mo",
+	UNIT_WEEK:          "wk",
+	UNIT_DAY:           "d",
+	UNIT_HOUR:          "h",
+	UNIT_MINUTE:        "min",
+	UNIT_SECOND:        "s",
+	UNIT_DEGREE:        "°",
+	UNIT_DEGREE_C:      "°C",
+	UNIT_CURRENCY:      "currency",
+	UNIT_METRE:         "m",
+	UNIT_METRE_PER_SEC: "m/s",
+	UNIT_CUBIC_METRE:   "m³",
+	14:                 "m³",
+	15:                 "m³/h",
+	16:                 "m³/h",
+	17:                 "m³/d",
+	18:                 "m³/d",
+	UNIT_LITRE:         "l",
+	UNIT_KILOGRAM:      "kg",
+	UNIT_NEWTON:        "N",
+	22:                 "Nm",
+	UNIT_PASCAL:        "Pa",
+	UNIT_BAR:           "bar",
+	UNIT_JOULE:         "J",
+	26:                 "J/h",
+	UNIT_WATT:          "W",
+	UNIT_VOLT_AMPERE:   "VA",
+	UNIT_VAR:           "var",
+	UNIT_WATT_HOUR:     "Wh",
+	UNIT_VA_HOUR:       "VAh",
+	UNIT_VAR_HOUR:      "varh",
+	UNIT_AMPERE:        "A",
+	UNIT_COULOMB:       "C",
+	UNIT_VOLT:          "V",
+	36:                 "V/m",
+	37:                 "F",
+	UNIT_OHM:           "Ω",
+	39:                 "Ωm²/m",
+	40:                 "Wb",
+	41:                 "T",
+	42:                 "A/m",
+	43:                 "H",
+	UNIT_HERTZ:         "Hz",
+	45:                 "1/(Wh)",
+	46:                 "1/(varh)",
+	47:                 "1/(VAh)",
+	48:                 "V²h",
+	49:                 "A²h",
+	50:                 "kg/s",
+	51:                 "S",
+	UNIT_KELVIN:        "K",
+	53:                 "1/(V²h)",
+	54:                 "1/(A²h)",
+	55:                 "1/m³",
+	UNIT_PERCENT:       "%",
+	UNIT_AMPERE_HOUR:   "Ah",
+}
+
+// UnitString returns the symbol of the given unit code or an empty string if the code is unknown
+// or dimensionless
+func UnitString(unit uint8) string {
+	return unitNames[unit]
+}
+
+// UnitString returns the symbol of the entry's unit, e.g. "Wh" or "W"
+func (le *ListEntry) UnitString() string {
+	return UnitString(le.Unit)
+}
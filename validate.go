@@ -0,0 +1,35 @@
+package gosml
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnitOnNonNumeric means that a list entry carries a unit or scaler although its value isn't
+// numeric, which violates the SML specification
+var ErrUnitOnNonNumeric = errors.New("unit or scaler set on non-numeric value")
+
+// EntryError is reported to the error callback for list entries that were rejected
+type EntryError struct {
+	Entry *ListEntry
+	Err   error
+}
+
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("entry % x: %v", []byte(e.Entry.ObjName), e.Err)
+}
+
+func (e *EntryError) Unwrap() error {
+	return e.Err
+}
+
+// checkEntry validates le against the type constraints of the SML specification
+func checkEntry(le *ListEntry) error {
+	if !le.IsNumeric() && (le.Unit != 0 || le.scaler != 0) {
+		return ErrUnitOnNonNumeric
+	}
+	if le.valTimeKind != TIME_KIND_NONE && !le.valTimeKind.known() {
+		return fmt.Errorf("valTime: %w %d", ErrUnknownTimeKind, le.valTimeKind)
+	}
+	return nil
+}
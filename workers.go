@@ -0,0 +1,84 @@
+package gosml
+
+import (
+	"bufio"
+	"sync"
+)
+
+// WithParseWorkers makes Read parse files on n goroutines while framing continues on another one,
+// which increases the throughput of high-rate streams, e.g. when aggregating many meters. Callbacks
+// are still called sequentially on the calling goroutine and in stream order. Note that the reader
+// is read ahead of the file being processed. When Read returns early, e.g. due to
+// WithMaxConsecutiveErrors or a deadline, it waits until the file being read ahead is complete, so
+// it may block on r like reads of the next file would, and files read ahead are dropped. All
+// goroutines have ended when Read returns, so r can be used afterwards. n <= 1 parses on the
+// calling goroutine, which is the default.
+func WithParseWorkers(n int) ReadOption {
+	return func(o *options) {
+		o.parseWorkers = n
+	}
+}
+
+// readParallel works like the loop of ReadWithStats but reads files on a separate goroutine and
+// parses them on o.parseWorkers goroutines. Results are handled in stream order.
+func (o *options) readParallel(r *bufio.Reader) (ReadStats, error) {
+	// files in stream order, bounding the read ahead
+	files := make(chan *readResult, 2*o.parseWorkers)
+	jobs := make(chan *readResult, o.parseWorkers)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	config := o.parseConfig()
+	wg.Add(o.parseWorkers + 1)
+	for i := 0; i < o.parseWorkers; i++ {
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				file.messages, file.parseErr = parseMessages(file.fileBytes[8:len(file.fileBytes)-8], config)
+				close(file.parsed)
+			}
+		}()
+	}
+
+	go func() {
+		defer wg.Done()
+		defer close(files)
+		defer close(jobs)
+		for {
+			file := &readResult{}
+			file.fileBytes, file.skipped, file.consumed, file.err = readFileCounted(r, o.maxSkip())
+			if file.err == nil {
+				file.parsed = make(chan struct{})
+				select {
+				case jobs <- file:
+				case <-done:
+					return
+				}
+			}
+			select {
+			case files <- file:
+			case <-done:
+				return
+			}
+			if file.err != nil && file.err != ErrSequenceTooLong && file.err != ErrUnrecognizedSequence &&
+				file.err != ErrNoBeginSequence {
+				return
+			}
+		}
+	}()
+
+	for !o.deadlinePassed() {
+		file, ok := <-files
+		if !ok {
+			break
+		}
+		if done, err := o.handleRead(file); done || err != nil {
+			return o.stats, err
+		}
+	}
+	return o.stats, nil
+}